@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+	"url-shortener/internal/app"
 	"url-shortener/internal/cache"
 	"url-shortener/internal/config"
+	"url-shortener/internal/health"
 	"url-shortener/internal/lib/generator"
 	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/metrics"
 	"url-shortener/internal/repository"
 	"url-shortener/internal/repository/memory"
 	"url-shortener/internal/repository/postgres"
+	"url-shortener/internal/repository/redis"
+	"url-shortener/internal/repository/sqlite"
 	"url-shortener/internal/routers"
 	"url-shortener/internal/services"
 )
@@ -24,10 +38,14 @@ import (
 // @BasePath	/api/v1
 // @accept		json
 // @produce	json
+// @securityDefinitions.apikey	AdminToken
+// @in							header
+// @name						Authorization
+// @description				"Bearer" followed by APP_ADMIN_TOKEN, required by the routes changing links.
 func main() {
 	// Parse command-line arguments
 	var storageType, cacheType string
-	flag.StringVar(&storageType, "storage-type", "memory", "Type of storage (memory, postgres)")
+	flag.StringVar(&storageType, "storage-type", "memory", "Type of storage (memory, postgres, sqlite, redis)")
 	flag.StringVar(&cacheType, "cache-type", "redis", "Type of cache (redis, none)")
 	flag.Parse()
 
@@ -36,68 +54,405 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if err := cfg.ValidateStorage(storageType); err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
 
 	// Setup logger
 	sLog := logger.Setup(cfg.App.Env)
 
 	// Initialize dependencies
-	linkService, err := initDependencies(cfg, storageType, cacheType)
+	deps, err := initDependencies(sLog, cfg, storageType, cacheType)
 	if err != nil {
 		log.Fatalf("Failed to initialize dependencies: %v", err)
 	}
 
 	// Initialize and start the router
-	r := routers.InitRouter(sLog, linkService)
-	if err := r.Run(fmt.Sprintf("%s:%d", cfg.App.Host, cfg.App.Port)); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	r := routers.InitRouter(sLog, deps.linkService, deps.analyticsService, deps.health, deps.metrics, cfg.App)
+	srv := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", cfg.App.Host, cfg.App.Port),
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		sLog.Info("starting server", slog.String("addr", srv.Addr))
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			deps.close(sLog)
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	case <-ctx.Done():
+		stop()
+		sLog.Info("shutting down, draining connections", slog.Int("timeout_ms", cfg.App.ShutdownTimeoutMs))
+		shutdown(sLog, srv, deps, time.Duration(cfg.App.ShutdownTimeoutMs)*time.Millisecond)
+	}
+}
+
+// shutdown stops accepting requests and waits for the in-flight ones up to drainTimeout,
+// then stops the services and closes the cache and repositories in that order.
+func shutdown(log *slog.Logger, srv *http.Server, deps *dependencies, drainTimeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Error("failed to drain connections, closing them", sl.Err(err))
+		_ = srv.Close()
+	}
+
+	deps.close(log)
+	log.Info("server stopped")
+}
+
+// dependencies holds everything the router is built from, in the order it is closed.
+type dependencies struct {
+	health           *health.Registry
+	metrics          *metrics.Metrics
+	linkService      services.Shortener
+	analyticsService *services.AnalyticsService
+	keyPool          *services.KeyPool
+	edge             repository.LinksRepo
+	cache            cache.Cache
+	linkRepo         repository.LinksRepo
+}
+
+// close flushes the services and releases the connections of the cache and repositories.
+// Dependencies that were not built are skipped, so it also cleans up a failed initialization.
+func (d *dependencies) close(log *slog.Logger) {
+	if d.analyticsService != nil {
+		d.analyticsService.Close()
+	}
+	if d.keyPool != nil {
+		if err := d.keyPool.Close(); err != nil {
+			log.Error("failed to close key pool", sl.Err(err))
+		}
+	}
+
+	closers := []struct {
+		name string
+		dep  any
+	}{
+		{"edge tier", d.edge},
+		{"cache", d.cache},
+		{"repository", d.linkRepo},
+	}
+	for _, c := range closers {
+		if closer, ok := c.dep.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Error("failed to close "+c.name, sl.Err(err))
+			}
+		}
 	}
 }
 
-func initDependencies(cfg *config.Config, storageType, cacheType string) (*services.LinkService, error) {
-	// Initialize link repository
-	linkRepo, err := initLinkRepo(storageType, cfg.Database)
+func initDependencies(log *slog.Logger, cfg *config.Config, storageType, cacheType string) (_ *dependencies, err error) {
+	// Close what was built so far when a later step fails
+	deps := &dependencies{}
+	defer func() {
+		if err != nil {
+			deps.close(log)
+		}
+	}()
+
+	// Load the blocklist and word lists first
+	blocklist, err := app.Blocklist(cfg.App)
+	if err != nil {
+		return nil, fmt.Errorf("blocklist loading error: %w", err)
+	}
+	wordGenerator, maxWordLinkSize, err := app.WordGenerator(cfg.App, blocklist)
+	if err != nil {
+		return nil, fmt.Errorf("word generator initialization error: %w", err)
+	}
+
+	// Initialize link and click repositories, sized for the longest short link of any kind
+	linkRepo, clicksRepo, err := initRepos(storageType, cfg, app.MaxShortLinkLength(cfg.App, maxWordLinkSize))
 	if err != nil {
 		return nil, fmt.Errorf("link repo initialization error: %w", err)
 	}
+	deps.linkRepo = linkRepo
 
 	// Initialize cache
 	cache, err := initCache(cacheType, cfg.Cache)
 	if err != nil {
 		return nil, fmt.Errorf("cache initialization error: %w", err)
 	}
+	deps.cache = cache
+
+	// Wrap the backends with instrumentation, the raw ones are kept for health checks and closing
+	m := metrics.New()
+	instrumentedLinkRepo := metrics.InstrumentLinksRepo(linkRepo, storageType, m)
+	instrumentedClicksRepo := metrics.InstrumentClicksRepo(clicksRepo, storageType, m)
+	instrumentedCache := cache
+	if cache != nil {
+		instrumentedCache = metrics.InstrumentCache(cache, m)
+	}
+
+	// Serve links from a bounded local tier in front of the storage when enabled
+	var servedLinkRepo repository.LinksRepo = instrumentedLinkRepo
+	if cfg.Memory.Edge {
+		edge, err := memory.NewBoundedLinksRepo(boundedConfig(cfg.Memory), instrumentedLinkRepo)
+		if err != nil {
+			return nil, fmt.Errorf("edge tier initialization error: %w", err)
+		}
+		servedLinkRepo = edge
+		deps.edge = edge
+		m.RegisterMemoryLinks(edge)
+	} else if bounded, ok := linkRepo.(*memory.BoundedLinksRepo); ok {
+		m.RegisterMemoryLinks(bounded)
+	}
 
 	// Initialize short link generator and service
-	generator := generator.NewRandomGenerator(cfg.App.ShortLinkAlphabet)
+	linkGenerator, err := initGenerator(cfg.App, blocklist, linkRepo, cache)
+	if err != nil {
+		return nil, fmt.Errorf("link generator initialization error: %w", err)
+	}
+	statsGenerator := linkGenerator
+	if filtered, ok := linkGenerator.(*generator.FilteredGenerator); ok {
+		statsGenerator = filtered.Unwrap()
+	}
+
+	// Hand out pre-generated keys of the configured generator when the key pool is enabled
+	var keyPool *services.KeyPool
+	if cfg.KeyPool.Enabled {
+		if keyPool, err = initKeyPool(cfg, linkRepo, linkGenerator); err != nil {
+			return nil, fmt.Errorf("key pool initialization error: %w", err)
+		}
+		deps.keyPool = keyPool
+		linkGenerator = keyPool
+		m.RegisterKeyPool(keyPool)
+	}
 	linkService, err := services.NewLinkService(
-		linkRepo,
-		cache,
-		generator,
+		servedLinkRepo,
+		instrumentedCache,
+		linkGenerator,
 		cfg.App.ShortLinkAlphabet,
-		cfg.App.ShortLinkLength,
+		services.LengthPolicy{
+			Initial:         cfg.App.ShortLinkLength,
+			Min:             cfg.App.ShortLinkMinLength,
+			Max:             cfg.App.ShortLinkMaxLength,
+			GrowthThreshold: cfg.App.LinkGrowthThreshold,
+			GrowthWindow:    cfg.App.LinkGrowthWindow,
+		},
 		cfg.App.Domain,
+		services.AliasPolicy{
+			MinLength: cfg.App.AliasMinLength,
+			MaxLength: cfg.App.AliasMaxLength,
+			Reserved:  cfg.App.ReservedAliases,
+		},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("link service initialization error: %w", err)
 	}
+	linkService.RegisterStyle("words", wordGenerator)
 
-	return linkService, nil
+	// Initialize click analytics
+	analyticsService, err := services.NewAnalyticsService(
+		instrumentedClicksRepo,
+		servedLinkRepo,
+		cfg.Analytics.BufferSize,
+		cfg.Analytics.BatchSize,
+		time.Duration(cfg.Analytics.FlushIntervalMs)*time.Millisecond,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("analytics service initialization error: %w", err)
+	}
+	m.RegisterDroppedClicks(analyticsService)
+	m.RegisterLinkLength(linkService)
+	m.RegisterCollisions(linkService, storageType)
+	if reporter, ok := statsGenerator.(generator.CollisionReporter); ok {
+		m.RegisterGeneratorStats(reporter)
+	}
+
+	// Register the readiness checks of the backends that provide one
+	healthRegistry := health.NewRegistry(time.Duration(cfg.App.HealthTimeoutMs) * time.Millisecond)
+	if checker, ok := linkRepo.(health.Checker); ok {
+		healthRegistry.Register("storage", checker)
+	}
+	if checker, ok := cache.(health.Checker); ok {
+		healthRegistry.Register("cache", checker)
+	}
+
+	deps.health = healthRegistry
+	deps.metrics = m
+	deps.linkService = metrics.InstrumentShortener(linkService, m)
+	deps.analyticsService = analyticsService
+	return deps, nil
 }
 
-func initLinkRepo(storageType string, dbCfg config.DatabaseConfig) (repository.LinksRepo, error) {
+func initRepos(storageType string, cfg *config.Config, maxShortLinkSize int) (repository.LinksRepo, repository.ClicksRepo, error) {
 	switch storageType {
 	case "memory":
-		return memory.NewMemoryLinksRepo(), nil
+		if cfg.Memory.MaxEntries > 0 || cfg.Memory.MaxBytes > 0 {
+			if cfg.Memory.Edge {
+				return nil, nil, fmt.Errorf("MEMORY_EDGE requires a storage other than memory")
+			}
+			linkRepo, err := memory.NewBoundedLinksRepo(boundedConfig(cfg.Memory), nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			return linkRepo, memory.NewMemoryClicksRepo(), nil
+		}
+		if cfg.Memory.DataDir == "" {
+			return memory.NewMemoryLinksRepo(), memory.NewMemoryClicksRepo(), nil
+		}
+		linkRepo, err := memory.NewDurableLinksRepo(cfg.Memory.DataDir, memory.DurableConfig{
+			Fsync:            memory.FsyncPolicy(cfg.Memory.Fsync),
+			FsyncInterval:    time.Duration(cfg.Memory.FsyncIntervalMs) * time.Millisecond,
+			SnapshotInterval: time.Duration(cfg.Memory.SnapshotIntervalMs) * time.Millisecond,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return linkRepo, memory.NewMemoryClicksRepo(), nil
 	case "postgres":
-		return postgres.NewPostgresLinksRepo(
-			dbCfg.Host,
-			dbCfg.Port,
-			dbCfg.User,
-			dbCfg.Password,
-			dbCfg.Name,
-			"links", // Can be extracted as a configuration parameter
+		conn, tables := app.PostgresSettings(cfg.Database, maxShortLinkSize)
+		linkRepo, err := postgres.NewPostgresLinksRepo(
+			conn,
+			tables,
+			cfg.Database.Migrate,
+			time.Duration(cfg.Database.QueryTimeoutMs)*time.Millisecond,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		return linkRepo, postgres.NewPostgresClicksRepo(linkRepo), nil
+	case "redis":
+		linkRepo, err := redis.NewRedisLinksRepo(
+			cfg.Cache.Host,
+			cfg.Cache.Port,
+			cfg.Cache.Password,
+			cfg.Cache.DB,
+			cfg.Redis.Prefix,
+			time.Duration(cfg.Cache.TimeoutMs)*time.Millisecond,
 		)
+		if err != nil {
+			return nil, nil, err
+		}
+		return linkRepo, memory.NewMemoryClicksRepo(), nil
+	case "sqlite":
+		linkRepo, err := sqlite.NewSQLiteLinksRepo(
+			cfg.SQLite.Path,
+			"links",
+			time.Duration(cfg.SQLite.QueryTimeoutMs)*time.Millisecond,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		clicksRepo, err := sqlite.NewSQLiteClicksRepo(linkRepo, "link_clicks")
+		if err != nil {
+			linkRepo.Close()
+			return nil, nil, err
+		}
+		return linkRepo, clicksRepo, nil
 	default:
-		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
+		return nil, nil, fmt.Errorf("unsupported storage type: %s", storageType)
+	}
+}
+
+// boundedConfig maps the memory configuration to the limits of a bounded repository.
+func boundedConfig(memoryCfg config.MemoryConfig) memory.BoundedConfig {
+	return memory.BoundedConfig{
+		Policy:     memory.EvictionPolicy(memoryCfg.Eviction),
+		MaxEntries: memoryCfg.MaxEntries,
+		MaxBytes:   memoryCfg.MaxBytes,
+		TTL:        time.Duration(memoryCfg.EdgeTTLMs) * time.Millisecond,
+	}
+}
+
+// initGenerator creates the configured generator and wraps it with the blocklist and
+// look-alike filter when either is enabled.
+func initGenerator(appCfg config.AppConfig, blocklist []string, linkRepo repository.LinksRepo, linkCache cache.Cache) (generator.Generator, error) {
+	alphabet := appCfg.ShortLinkAlphabet
+	if appCfg.LinkUnambiguous {
+		alphabet = generator.UnambiguousAlphabet(alphabet)
+	}
+
+	var g generator.Generator
+	switch appCfg.LinkGenerator {
+	case "random":
+		randomGenerator, err := generator.NewRandomGenerator(alphabet)
+		if err != nil {
+			return nil, err
+		}
+		g = randomGenerator
+	case "hash":
+		hashGenerator, err := generator.NewHashGenerator(alphabet, []byte(appCfg.LinkHashKey))
+		if err != nil {
+			return nil, err
+		}
+		g = hashGenerator
+	case "sequence":
+		counter, err := initCounter(appCfg, linkRepo, linkCache)
+		if err != nil {
+			return nil, err
+		}
+		sequenceGenerator, err := generator.NewSequenceGenerator(alphabet, counter, []byte(appCfg.LinkSequenceKey))
+		if err != nil {
+			return nil, err
+		}
+		g = sequenceGenerator
+	default:
+		return nil, fmt.Errorf("unsupported link generator: %s", appCfg.LinkGenerator)
+	}
+
+	if len(blocklist) > 0 || appCfg.LinkUnambiguous {
+		g = generator.NewFilteredGenerator(g, appCfg.ShortLinkAlphabet, blocklist, appCfg.LinkUnambiguous)
+	}
+
+	return g, nil
+}
+
+// initKeyPool creates the pool of keys generated by source in the storage of the links.
+func initKeyPool(cfg *config.Config, linkRepo repository.LinksRepo, source generator.Generator) (*services.KeyPool, error) {
+	var keysRepo repository.KeysRepo
+	switch repo := linkRepo.(type) {
+	case *memory.MemoryLinksRepo:
+		keysRepo = memory.NewMemoryKeysRepo(repo)
+	case *memory.DurableLinksRepo:
+		keysRepo = memory.NewMemoryKeysRepo(repo.MemoryLinksRepo)
+	case *postgres.PostgresLinksRepo:
+		keysRepo = postgres.NewPostgresKeysRepo(repo)
+	default:
+		return nil, fmt.Errorf("key pool is not supported by the storage")
+	}
+
+	return services.NewKeyPool(keysRepo, source, services.KeyPoolConfig{
+		KeySize:       cfg.App.ShortLinkLength,
+		LeaseSize:     cfg.KeyPool.LeaseSize,
+		LowWater:      cfg.KeyPool.LowWater,
+		RefillSize:    cfg.KeyPool.RefillSize,
+		CheckInterval: time.Duration(cfg.KeyPool.CheckIntervalMs) * time.Millisecond,
+	})
+}
+
+// initCounter returns the ID source of the sequence generator. The Postgres and Redis counters
+// reuse the connections of the storage and cache, so they require them to be in use.
+func initCounter(appCfg config.AppConfig, linkRepo repository.LinksRepo, linkCache cache.Cache) (generator.Counter, error) {
+	switch appCfg.LinkCounter {
+	case "memory":
+		return generator.NewAtomicCounter(0), nil
+	case "postgres":
+		pgRepo, ok := linkRepo.(*postgres.PostgresLinksRepo)
+		if !ok {
+			return nil, fmt.Errorf("postgres counter requires postgres storage")
+		}
+		return postgres.NewPostgresCounter(pgRepo, appCfg.LinkCounterName)
+	case "redis":
+		redisCache, ok := linkCache.(*cache.RedisCache)
+		if !ok {
+			return nil, fmt.Errorf("redis counter requires redis cache")
+		}
+		return cache.NewRedisCounter(redisCache, "counter:"+appCfg.LinkCounterName)
+	default:
+		return nil, fmt.Errorf("unsupported link counter: %s", appCfg.LinkCounter)
 	}
 }
 
@@ -110,7 +465,8 @@ func initCache(cacheType string, cacheCfg config.CacheConfig) (cache.Cache, erro
 			cacheCfg.Password,
 			cacheCfg.DB,
 			cacheCfg.TTL,
-		), nil
+			time.Duration(cacheCfg.TimeoutMs)*time.Millisecond,
+		)
 	case "none":
 		return nil, nil
 	default:
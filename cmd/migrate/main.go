@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+	"url-shortener/internal/app"
+	"url-shortener/internal/config"
+	"url-shortener/internal/repository/postgres"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up        apply all pending migrations
+  down [N]  revert the last N applied migrations (1 by default)
+  version   print the applied schema version
+`
+
+// migrate applies the Postgres schema migrations of the service, configured by the same
+// environment variables. Set POSTGRES_MIGRATE=false to leave migrating to this command.
+func main() {
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// The short link column is sized like the service does
+	blocklist, err := app.Blocklist(cfg.App)
+	if err != nil {
+		log.Fatalf("Failed to load blocklist: %v", err)
+	}
+	_, maxWordLinkSize, err := app.WordGenerator(cfg.App, blocklist)
+	if err != nil {
+		log.Fatalf("Failed to load word lists: %v", err)
+	}
+	conn, tables := app.PostgresSettings(cfg.Database, app.MaxShortLinkLength(cfg.App, maxWordLinkSize))
+	db, err := postgres.OpenDB(conn, time.Duration(cfg.Database.QueryTimeoutMs)*time.Millisecond)
+	if err != nil {
+		log.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := postgres.NewMigrator(db, tables)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		log.Printf("Applied %d migrations", applied)
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			if steps, err = strconv.Atoi(flag.Arg(1)); err != nil || steps <= 0 {
+				log.Fatalf("Invalid number of migrations to revert: %s", flag.Arg(1))
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatalf("Failed to revert migrations: %v", err)
+		}
+		log.Printf("Reverted %d migrations", reverted)
+	case "version":
+		version, pending, err := migrator.Version(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read schema version: %v", err)
+		}
+		log.Printf("Schema version %d, %d pending migrations, latest is %d", version, pending, migrator.Latest())
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
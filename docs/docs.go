@@ -26,9 +26,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/": {
+        "/link/": {
             "post": {
-                "description": "Saves a new short URL for the provided original URL.",
+                "description": "Saves a new short URL for the provided original URL. An optional alias is used as the short link instead of a generated one, an optional style picks how it is generated (e.g. \"words\" for brave-otter-42). The link expires at expires_at or after ttl_seconds (at most 100 years) when either is given, a URL that is already shortened with another expiration is a conflict. As ttl_seconds is counted from the request, resubmitting it always conflicts, pass expires_at for idempotent retries.",
                 "consumes": [
                     "application/json"
                 ],
@@ -63,6 +63,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -72,7 +78,53 @@ const docTemplate = `{
                 }
             }
         },
-        "/{link}": {
+        "/link/batch": {
+            "post": {
+                "description": "Saves short URLs for all provided original URLs. Every item gets its own result in request order, failed items do not abort the batch.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Save a batch of short URLs",
+                "parameters": [
+                    {
+                        "description": "Original URLs to shorten",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handlers_url.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handlers_url.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/link/{link}": {
             "get": {
                 "description": "Retrieves the original URL associated with the provided short URL.",
                 "consumes": [
@@ -107,6 +159,279 @@ const docTemplate = `{
                             "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Deletes the short URL, its original URL can be shortened again afterwards.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Delete a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "link",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/link/{link}/disable": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Disables the short URL, it is kept but no longer redirects.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Disable a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "link",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/link/{link}/enable": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Re-enables a previously disabled short URL.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "summary": "Enable a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "link",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/link/{link}/stats": {
+            "get": {
+                "description": "Returns the total number of clicks on the short URL in [from, to) and a histogram with one entry per bucket. The range defaults to the last 7 days.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Retrieve click statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL",
+                        "name": "link",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range start, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "minute",
+                            "hour",
+                            "day"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Bucket width",
+                        "name": "bucket",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handlers_stats.StatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/url-shortener_internal_lib_api_response.Response"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -124,6 +449,74 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "internal_handlers_stats.StatsResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/url-shortener_internal_domain.ClickBucket"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handlers_url.BatchItem": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handlers_url.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "urls": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_handlers_url.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_handlers_url.BatchItem"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "internal_handlers_url.GetResponse": {
             "type": "object",
             "properties": {
@@ -144,6 +537,21 @@ const docTemplate = `{
                 "url"
             ],
             "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "style": {
+                    "type": "string"
+                },
+                "ttl_seconds": {
+                    "description": "At most 100 years",
+                    "type": "integer",
+                    "maximum": 3153600000,
+                    "minimum": 1
+                },
                 "url": {
                     "type": "string"
                 }
@@ -163,6 +571,17 @@ const docTemplate = `{
                 }
             }
         },
+        "url-shortener_internal_domain.ClickBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
         "url-shortener_internal_lib_api_response.Response": {
             "type": "object",
             "properties": {
@@ -174,6 +593,14 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "\"Bearer\" followed by APP_ADMIN_TOKEN, required by the routes changing links.",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,73 @@
+// Package app maps the configuration to the settings shared by the service and the migrate
+// command, so both size and reach the schema the same way.
+package app
+
+import (
+	"time"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/generator"
+	"url-shortener/internal/repository/postgres"
+)
+
+// Blocklist loads the words generated short links must not contain, none when no file is set.
+func Blocklist(appCfg config.AppConfig) ([]string, error) {
+	if appCfg.LinkBlocklistFile == "" {
+		return nil, nil
+	}
+	return generator.LoadBlocklist(appCfg.LinkBlocklistFile)
+}
+
+// WordGenerator creates the generator of the "words" style from the embedded word lists
+// or the configured files, filtered by the blocklist. It also returns the length of the
+// longest short link it creates.
+func WordGenerator(appCfg config.AppConfig, blocklist []string) (generator.Generator, int, error) {
+	adjectives, nouns, err := generator.LoadWordLists(appCfg.WordsAdjectivesFile, appCfg.WordsNounsFile)
+	if err != nil {
+		return nil, 0, err
+	}
+	wordGenerator, err := generator.NewWordGenerator(adjectives, nouns, appCfg.WordsDigits)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(blocklist) > 0 {
+		return generator.NewFilteredGenerator(wordGenerator, appCfg.ShortLinkAlphabet, blocklist, false), wordGenerator.MaxLength(), nil
+	}
+	return wordGenerator, wordGenerator.MaxLength(), nil
+}
+
+// MaxShortLinkLength returns the length of the longest short link of any kind, generated,
+// alias or words, the short link column is sized to it.
+func MaxShortLinkLength(appCfg config.AppConfig, maxWordLinkSize int) int {
+	return max(appCfg.ShortLinkMaxLength, appCfg.AliasMaxLength, maxWordLinkSize)
+}
+
+// PostgresSettings maps the database configuration to the connection and table settings.
+func PostgresSettings(dbCfg config.DatabaseConfig, maxShortLinkSize int) (postgres.ConnConfig, postgres.Tables) {
+	conn := postgres.ConnConfig{
+		DSN:              dbCfg.DSN,
+		Host:             dbCfg.Host,
+		Port:             dbCfg.Port,
+		User:             dbCfg.User,
+		Password:         dbCfg.Password,
+		Name:             dbCfg.Name,
+		SSLMode:          dbCfg.SSLMode,
+		SSLRootCert:      dbCfg.SSLRootCert,
+		SSLCert:          dbCfg.SSLCert,
+		SSLKey:           dbCfg.SSLKey,
+		Schema:           dbCfg.Schema,
+		StatementTimeout: time.Duration(dbCfg.StatementTimeoutMs) * time.Millisecond,
+		MaxOpenConns:     dbCfg.MaxOpenConns,
+		MaxIdleConns:     dbCfg.MaxIdleConns,
+		ConnMaxLifetime:  time.Duration(dbCfg.ConnMaxLifetimeMs) * time.Millisecond,
+		ConnMaxIdleTime:  time.Duration(dbCfg.ConnMaxIdleTimeMs) * time.Millisecond,
+	}
+	tables := postgres.Tables{
+		Schema:    dbCfg.Schema,
+		Links:     dbCfg.LinksTable,
+		Clicks:    dbCfg.ClicksTable,
+		Keys:      dbCfg.KeysTable,
+		MaxLength: maxShortLinkSize,
+	}
+	return conn, tables
+}
@@ -1,6 +1,28 @@
 package cache
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key is not cached.
+var ErrMiss = errors.New("cache miss")
+
 type Cache interface {
-	Get(string) (string, error)
-	Set(string, string) error
+	Get(context.Context, string) (string, error)
+	// Set stores the value, keeping it no longer than maxTTL when maxTTL is positive.
+	Set(ctx context.Context, key string, value string, maxTTL time.Duration) error
+	Delete(context.Context, string) error
+}
+
+// Unwrap returns the cache c decorates, e.g. with metrics, or c itself when it decorates none.
+func Unwrap(c Cache) Cache {
+	for {
+		u, ok := c.(interface{ Unwrap() Cache })
+		if !ok {
+			return c
+		}
+		c = u.Unwrap()
+	}
 }
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrEvictingRedis = errors.New("redis may evict the counter")
+
+// RedisCounter hands out IDs with INCR on a single key, so they are unique across instances.
+type RedisCounter struct {
+	client  *redis.Client
+	key     string
+	timeout time.Duration
+}
+
+// NewRedisCounter counts in key, sharing the connection pool and timeout of the cache.
+// The key must not look like a short link, e.g. it should contain a character outside of the alphabet.
+//
+// The counter has no TTL, so it is only safe from eviction when Redis evicts keys with a TTL
+// at most. An evicted counter starts over and reissues the IDs of stored links, so an
+// allkeys-* maxmemory-policy fails with ErrEvictingRedis. A policy that cannot be read,
+// e.g. as CONFIG is disabled, is logged and left to the operator.
+func NewRedisCounter(c *RedisCache, key string) (*RedisCounter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	policy, err := maxmemoryPolicy(ctx, c.client)
+	if err != nil {
+		log.Default().Printf("Failed to check the Redis maxmemory-policy, it must not evict keys without a TTL: %v", err)
+	} else if strings.HasPrefix(policy, "allkeys-") {
+		return nil, fmt.Errorf("%w: maxmemory-policy is %s, use noeviction or a volatile-* policy", ErrEvictingRedis, policy)
+	}
+
+	return &RedisCounter{client: c.client, key: key, timeout: c.timeout}, nil
+}
+
+func maxmemoryPolicy(ctx context.Context, client *redis.Client) (string, error) {
+	values, err := client.ConfigGet(ctx, "maxmemory-policy").Result()
+	if err != nil {
+		return "", err
+	}
+	if len(values) != 2 {
+		return "", fmt.Errorf("unexpected CONFIG GET reply: %v", values)
+	}
+	policy, _ := values[1].(string)
+	return policy, nil
+}
+
+func (r *RedisCounter) Next(ctx context.Context) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	id, err := r.client.Incr(ctx, r.key).Result()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), nil
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,24 +10,67 @@ import (
 )
 
 type RedisCache struct {
-	ttl    time.Duration
-	client *redis.Client
+	ttl     time.Duration
+	timeout time.Duration
+	client  *redis.Client
 }
 
-func NewRedisCache(host string, port int, password string, db int, ttl int) *RedisCache {
+// NewRedisCache creates a cache that keeps entries for ttl seconds and bounds
+// every call to Redis by timeout. It fails if Redis does not answer a PING.
+func NewRedisCache(host string, port int, password string, db int, ttl int, timeout time.Duration) (*RedisCache, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", host, port),
 		Password: password,
 		DB:       db,
 	})
 
-	return &RedisCache{client: client, ttl: time.Duration(ttl) * time.Second}
+	r := &RedisCache{client: client, ttl: time.Duration(ttl) * time.Second, timeout: timeout}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := r.HealthCheck(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error pinging Redis: %w", err)
+	}
+
+	return r, nil
+}
+
+// HealthCheck reports whether Redis answers a PING.
+func (r *RedisCache) HealthCheck(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
 }
 
-func (r *RedisCache) Get(key string) (string, error) {
-	return r.client.Get(context.TODO(), key).Result()
+// Close closes the connections to Redis.
+func (r *RedisCache) Close() error {
+	return r.client.Close()
 }
 
-func (r *RedisCache) Set(key string, value string) error {
-	return r.client.Set(context.TODO(), key, value, r.ttl).Err()
+func (r *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrMiss
+	}
+	return value, err
+}
+
+func (r *RedisCache) Set(ctx context.Context, key string, value string, maxTTL time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	ttl := r.ttl
+	if maxTTL > 0 && (ttl <= 0 || maxTTL < ttl) {
+		ttl = maxTTL
+	}
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *RedisCache) Delete(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	return r.client.Del(ctx, key).Err()
 }
@@ -2,41 +2,137 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"url-shortener/internal/lib/generator"
 
 	"github.com/joho/godotenv"
+	"github.com/lib/pq"
+)
+
+// maxBatchSize and maxClicksBatchSize keep a batch insert within the 65535 bind parameters
+// Postgres allows per statement, it binds three per link and five per click.
+const (
+	maxBatchSize       = 65535 / 3
+	maxClicksBatchSize = 65535 / 5
 )
 
+// sqlIdentifier matches the identifiers that are safe to put into queries unquoted.
+var sqlIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
 type AppConfig struct {
-	Host              string
-	Port              int
-	Domain            string
-	ShortLinkLength   int
-	ShortLinkAlphabet string
-	Env               string
+	Host                string
+	Port                int
+	Domain              string
+	ShortLinkLength     int
+	ShortLinkMinLength  int
+	ShortLinkMaxLength  int
+	LinkGrowthThreshold float64
+	LinkGrowthWindow    int
+	ShortLinkAlphabet   string
+	LinkGenerator       string
+	LinkHashKey         string
+	LinkSequenceKey     string
+	LinkCounter         string
+	LinkCounterName     string
+	LinkBlocklistFile   string
+	LinkUnambiguous     bool
+	WordsAdjectivesFile string
+	WordsNounsFile      string
+	WordsDigits         int
+	RedirectCode        int
+	AliasMinLength      int
+	AliasMaxLength      int
+	ReservedAliases     []string
+	BatchMaxSize        int
+	AdminToken          string
+	ShutdownTimeoutMs   int
+	HealthTimeoutMs     int
+	Env                 string
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     int
-	Name     string
-	User     string
-	Password string
+	DSN                string
+	Host               string
+	Port               int
+	Name               string
+	User               string
+	Password           string
+	SSLMode            string
+	SSLRootCert        string
+	SSLCert            string
+	SSLKey             string
+	MaxOpenConns       int
+	MaxIdleConns       int
+	ConnMaxLifetimeMs  int
+	ConnMaxIdleTimeMs  int
+	StatementTimeoutMs int
+	QueryTimeoutMs     int
+	Schema             string
+	LinksTable         string
+	ClicksTable        string
+	KeysTable          string
+	Migrate            bool
+}
+
+type MemoryConfig struct {
+	DataDir            string
+	Fsync              string
+	FsyncIntervalMs    int
+	SnapshotIntervalMs int
+	MaxEntries         int
+	MaxBytes           int64
+	Eviction           string
+	Edge               bool
+	EdgeTTLMs          int
+}
+
+type SQLiteConfig struct {
+	Path           string
+	QueryTimeoutMs int
 }
 
 type CacheConfig struct {
-	Host     string
-	Port     int
-	Password string
-	DB       int
-	TTL      int
+	Host      string
+	Port      int
+	Password  string
+	DB        int
+	TTL       int
+	TimeoutMs int
+}
+
+type RedisStorageConfig struct {
+	Prefix string
+}
+
+type AnalyticsConfig struct {
+	BufferSize      int
+	BatchSize       int
+	FlushIntervalMs int
+}
+
+type KeyPoolConfig struct {
+	Enabled         bool
+	LeaseSize       int
+	LowWater        int
+	RefillSize      int
+	CheckIntervalMs int
 }
 
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Cache    CacheConfig
+	App       AppConfig
+	Database  DatabaseConfig
+	Memory    MemoryConfig
+	SQLite    SQLiteConfig
+	Cache     CacheConfig
+	Redis     RedisStorageConfig
+	Analytics AnalyticsConfig
+	KeyPool   KeyPoolConfig
 }
 
 // LoadConfig initializes and returns the full configuration.
@@ -45,30 +141,274 @@ func LoadConfig() (*Config, error) {
 		fmt.Println("Warning: .env file not found, using environment variables")
 	}
 
-	return &Config{
+	linkLength := getEnvAsInt("APP_LINK_LENGTH", 10)
+	cfg := &Config{
 		App: AppConfig{
-			Host:              getEnv("APP_HOST", "localhost"),
-			Port:              getEnvAsInt("APP_PORT", 8080),
-			Domain:            getEnv("APP_DOMAIN", "example.com"),
-			ShortLinkLength:   getEnvAsInt("APP_LINK_LENGTH", 10),
-			ShortLinkAlphabet: getEnv("APP_LINK_ALPHABET", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"),
-			Env:               getEnv("APP_ENV", "prod"),
+			Host:                getEnv("APP_HOST", "localhost"),
+			Port:                getEnvAsInt("APP_PORT", 8080),
+			Domain:              getEnv("APP_DOMAIN", "example.com"),
+			ShortLinkLength:     linkLength,
+			ShortLinkMinLength:  getEnvAsInt("APP_LINK_MIN_LENGTH", linkLength),
+			ShortLinkMaxLength:  getEnvAsInt("APP_LINK_MAX_LENGTH", max(linkLength, 16)),
+			LinkGrowthThreshold: getEnvAsFloat("APP_LINK_GROWTH_THRESHOLD", 0.05),
+			LinkGrowthWindow:    getEnvAsInt("APP_LINK_GROWTH_WINDOW", 1000),
+			ShortLinkAlphabet:   getEnv("APP_LINK_ALPHABET", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"),
+			LinkGenerator:       getEnv("APP_LINK_GENERATOR", "random"),
+			LinkHashKey:         getEnv("APP_LINK_HASH_KEY", ""),
+			LinkSequenceKey:     getEnv("APP_LINK_SEQUENCE_KEY", ""),
+			LinkCounter:         getEnv("APP_LINK_COUNTER", "memory"),
+			LinkCounterName:     getEnv("APP_LINK_COUNTER_NAME", "link_ids"),
+			LinkBlocklistFile:   getEnv("APP_LINK_BLOCKLIST_FILE", ""),
+			LinkUnambiguous:     getEnvAsBool("APP_LINK_UNAMBIGUOUS", false),
+			WordsAdjectivesFile: getEnv("APP_WORDS_ADJECTIVES_FILE", ""),
+			WordsNounsFile:      getEnv("APP_WORDS_NOUNS_FILE", ""),
+			WordsDigits:         getEnvAsInt("APP_WORDS_DIGITS", 2),
+			RedirectCode:        getEnvAsInt("APP_REDIRECT_CODE", http.StatusFound),
+			AliasMinLength:      getEnvAsInt("APP_ALIAS_MIN_LENGTH", 4),
+			AliasMaxLength:      getEnvAsInt("APP_ALIAS_MAX_LENGTH", 32),
+			ReservedAliases:     getEnvAsSlice("APP_RESERVED_ALIASES", []string{"api", "swagger", "health", "healthz", "readyz", "metrics", "static", "admin"}),
+			BatchMaxSize:        getEnvAsInt("APP_BATCH_MAX_SIZE", 1000),
+			AdminToken:          getEnv("APP_ADMIN_TOKEN", ""),
+			ShutdownTimeoutMs:   getEnvAsInt("APP_SHUTDOWN_TIMEOUT_MS", 15000),
+			HealthTimeoutMs:     getEnvAsInt("APP_HEALTH_TIMEOUT_MS", 1000),
+			Env:                 getEnv("APP_ENV", "prod"),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("POSTGRES_HOST", "localhost"),
-			Port:     getEnvAsInt("POSTGRES_PORT", 5432),
-			Name:     getEnv("POSTGRES_DATABASE", "url_shortener"),
-			User:     getEnv("POSTGRES_USER", "postgres"),
-			Password: getEnv("POSTGRES_PASSWORD", ""),
+			DSN:                getEnv("POSTGRES_DSN", ""),
+			Host:               getEnv("POSTGRES_HOST", "localhost"),
+			Port:               getEnvAsInt("POSTGRES_PORT", 5432),
+			Name:               getEnv("POSTGRES_DATABASE", "url_shortener"),
+			User:               getEnv("POSTGRES_USER", "postgres"),
+			Password:           getEnv("POSTGRES_PASSWORD", ""),
+			SSLMode:            getEnv("POSTGRES_SSLMODE", ""),
+			SSLRootCert:        getEnv("POSTGRES_SSLROOTCERT", ""),
+			SSLCert:            getEnv("POSTGRES_SSLCERT", ""),
+			SSLKey:             getEnv("POSTGRES_SSLKEY", ""),
+			MaxOpenConns:       getEnvAsInt("POSTGRES_MAX_OPEN_CONNS", 20),
+			MaxIdleConns:       getEnvAsInt("POSTGRES_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetimeMs:  getEnvAsInt("POSTGRES_CONN_MAX_LIFETIME_MS", 1800000),
+			ConnMaxIdleTimeMs:  getEnvAsInt("POSTGRES_CONN_MAX_IDLE_TIME_MS", 300000),
+			StatementTimeoutMs: getEnvAsInt("POSTGRES_STATEMENT_TIMEOUT_MS", 0),
+			QueryTimeoutMs:     getEnvAsInt("POSTGRES_QUERY_TIMEOUT_MS", 3000),
+			Schema:             getEnv("POSTGRES_SCHEMA", ""),
+			LinksTable:         getEnv("POSTGRES_LINKS_TABLE", "links"),
+			ClicksTable:        getEnv("POSTGRES_CLICKS_TABLE", "link_clicks"),
+			KeysTable:          getEnv("POSTGRES_KEYS_TABLE", "link_keys"),
+			Migrate:            getEnvAsBool("POSTGRES_MIGRATE", true),
+		},
+		Memory: MemoryConfig{
+			DataDir:            getEnv("MEMORY_DATA_DIR", ""),
+			Fsync:              getEnv("MEMORY_FSYNC", "interval"),
+			FsyncIntervalMs:    getEnvAsInt("MEMORY_FSYNC_INTERVAL_MS", 1000),
+			SnapshotIntervalMs: getEnvAsInt("MEMORY_SNAPSHOT_INTERVAL_MS", 300000),
+			MaxEntries:         getEnvAsInt("MEMORY_MAX_ENTRIES", 0),
+			MaxBytes:           int64(getEnvAsInt("MEMORY_MAX_BYTES", 0)),
+			Eviction:           getEnv("MEMORY_EVICTION", "lru"),
+			Edge:               getEnvAsBool("MEMORY_EDGE", false),
+			EdgeTTLMs:          getEnvAsInt("MEMORY_EDGE_TTL_MS", 60000),
+		},
+		SQLite: SQLiteConfig{
+			Path:           getEnv("SQLITE_PATH", "url_shortener.db"),
+			QueryTimeoutMs: getEnvAsInt("SQLITE_QUERY_TIMEOUT_MS", 3000),
 		},
 		Cache: CacheConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     getEnvAsInt("REDIS_PORT", 6379),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getEnvAsInt("REDIS_DB", 0),
-			TTL:      getEnvAsInt("REDIS_TTL", 604800),
+			Host:      getEnv("REDIS_HOST", "localhost"),
+			Port:      getEnvAsInt("REDIS_PORT", 6379),
+			Password:  getEnv("REDIS_PASSWORD", ""),
+			DB:        getEnvAsInt("REDIS_DB", 0),
+			TTL:       getEnvAsInt("REDIS_TTL", 604800),
+			TimeoutMs: getEnvAsInt("REDIS_TIMEOUT_MS", 500),
+		},
+		Redis: RedisStorageConfig{
+			Prefix: getEnv("REDIS_STORAGE_PREFIX", "shortener:"),
+		},
+		Analytics: AnalyticsConfig{
+			BufferSize:      getEnvAsInt("ANALYTICS_BUFFER_SIZE", 10000),
+			BatchSize:       getEnvAsInt("ANALYTICS_BATCH_SIZE", 500),
+			FlushIntervalMs: getEnvAsInt("ANALYTICS_FLUSH_INTERVAL_MS", 1000),
 		},
-	}, nil
+		KeyPool: KeyPoolConfig{
+			Enabled:         getEnvAsBool("KEY_POOL_ENABLED", false),
+			LeaseSize:       getEnvAsInt("KEY_POOL_LEASE_SIZE", 100),
+			LowWater:        getEnvAsInt("KEY_POOL_LOW_WATER", 10000),
+			RefillSize:      getEnvAsInt("KEY_POOL_REFILL_SIZE", 10000),
+			CheckIntervalMs: getEnvAsInt("KEY_POOL_CHECK_INTERVAL_MS", 5000),
+		},
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// validate checks the configuration values that cannot be safely defaulted.
+func (c *Config) validate() error {
+	switch c.App.RedirectCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("APP_REDIRECT_CODE must be one of 301, 302, 307, 308, got %d", c.App.RedirectCode)
+	}
+	if c.App.ShortLinkMinLength <= 0 || c.App.ShortLinkMinLength > c.App.ShortLinkLength || c.App.ShortLinkLength > c.App.ShortLinkMaxLength {
+		return fmt.Errorf("APP_LINK_LENGTH must be within [APP_LINK_MIN_LENGTH, APP_LINK_MAX_LENGTH], got %d not in [%d, %d]",
+			c.App.ShortLinkLength, c.App.ShortLinkMinLength, c.App.ShortLinkMaxLength)
+	}
+	if c.App.LinkGrowthThreshold > 0 && c.App.LinkGrowthWindow <= 0 {
+		return fmt.Errorf("APP_LINK_GROWTH_WINDOW must be positive, got %d", c.App.LinkGrowthWindow)
+	}
+	alphabet := c.App.ShortLinkAlphabet
+	if c.App.LinkUnambiguous {
+		alphabet = generator.UnambiguousAlphabet(alphabet)
+	}
+	if utf8.RuneCountInString(alphabet) < 2 {
+		return fmt.Errorf("APP_LINK_ALPHABET must have at least 2 characters, not counting the look-alikes APP_LINK_UNAMBIGUOUS removes, got %q", c.App.ShortLinkAlphabet)
+	}
+	switch c.App.LinkGenerator {
+	case "random":
+	case "hash":
+		if c.App.LinkHashKey == "" {
+			return fmt.Errorf("APP_LINK_HASH_KEY is required by the hash link generator")
+		}
+	case "sequence":
+		if c.App.LinkSequenceKey == "" {
+			return fmt.Errorf("APP_LINK_SEQUENCE_KEY is required by the sequence link generator")
+		}
+		switch c.App.LinkCounter {
+		case "memory", "postgres", "redis":
+		default:
+			return fmt.Errorf("APP_LINK_COUNTER must be one of memory, postgres, redis, got %q", c.App.LinkCounter)
+		}
+		if !sqlIdentifier.MatchString(c.App.LinkCounterName) {
+			return fmt.Errorf("APP_LINK_COUNTER_NAME must be a lowercase SQL identifier, got %q", c.App.LinkCounterName)
+		}
+	default:
+		return fmt.Errorf("APP_LINK_GENERATOR must be one of random, hash, sequence, got %q", c.App.LinkGenerator)
+	}
+	if c.App.WordsDigits < 0 || c.App.WordsDigits > 9 {
+		return fmt.Errorf("APP_WORDS_DIGITS must be within [0, 9], got %d", c.App.WordsDigits)
+	}
+	if c.App.AliasMinLength <= 0 || c.App.AliasMinLength > c.App.AliasMaxLength {
+		return fmt.Errorf("invalid alias length range [%d, %d]", c.App.AliasMinLength, c.App.AliasMaxLength)
+	}
+	if c.Database.QueryTimeoutMs <= 0 || c.Cache.TimeoutMs <= 0 {
+		return fmt.Errorf("POSTGRES_QUERY_TIMEOUT_MS and REDIS_TIMEOUT_MS must be positive")
+	}
+	if err := c.Database.validate(); err != nil {
+		return err
+	}
+	if c.Memory.DataDir != "" {
+		switch c.Memory.Fsync {
+		case "always", "interval", "never":
+		default:
+			return fmt.Errorf("MEMORY_FSYNC must be one of always, interval, never, got %q", c.Memory.Fsync)
+		}
+		if c.Memory.FsyncIntervalMs <= 0 || c.Memory.SnapshotIntervalMs <= 0 {
+			return fmt.Errorf("MEMORY_FSYNC_INTERVAL_MS and MEMORY_SNAPSHOT_INTERVAL_MS must be positive")
+		}
+	}
+	if c.Memory.MaxEntries < 0 || c.Memory.MaxBytes < 0 || c.Memory.EdgeTTLMs < 0 {
+		return fmt.Errorf("MEMORY_MAX_ENTRIES, MEMORY_MAX_BYTES and MEMORY_EDGE_TTL_MS must not be negative")
+	}
+	if bounded := c.Memory.MaxEntries > 0 || c.Memory.MaxBytes > 0; bounded || c.Memory.Edge {
+		if c.Memory.Eviction != "lru" && c.Memory.Eviction != "lfu" {
+			return fmt.Errorf("MEMORY_EVICTION must be one of lru, lfu, got %q", c.Memory.Eviction)
+		}
+		if !bounded {
+			return fmt.Errorf("MEMORY_EDGE requires MEMORY_MAX_ENTRIES or MEMORY_MAX_BYTES")
+		}
+		if c.Memory.Edge && c.Memory.EdgeTTLMs == 0 {
+			return fmt.Errorf("MEMORY_EDGE_TTL_MS must be positive with MEMORY_EDGE")
+		}
+		if c.Memory.DataDir != "" {
+			return fmt.Errorf("MEMORY_DATA_DIR cannot be combined with MEMORY_MAX_ENTRIES or MEMORY_MAX_BYTES, evicted links would be persisted")
+		}
+	}
+	// The cache is keyed by the bare short links, "-" joins the words of the "words" style
+	if !strings.ContainsFunc(c.Redis.Prefix, func(r rune) bool { return r != '-' && !strings.ContainsRune(c.App.ShortLinkAlphabet, r) }) {
+		return fmt.Errorf("REDIS_STORAGE_PREFIX must contain a character outside of APP_LINK_ALPHABET and '-', it keeps the links apart from the cache, got %q", c.Redis.Prefix)
+	}
+	if c.SQLite.Path == "" || c.SQLite.QueryTimeoutMs <= 0 {
+		return fmt.Errorf("SQLITE_PATH must be set and SQLITE_QUERY_TIMEOUT_MS positive")
+	}
+	if c.App.ShutdownTimeoutMs <= 0 || c.App.HealthTimeoutMs <= 0 {
+		return fmt.Errorf("APP_SHUTDOWN_TIMEOUT_MS and APP_HEALTH_TIMEOUT_MS must be positive")
+	}
+	if c.KeyPool.Enabled {
+		if c.App.LinkGenerator == "hash" {
+			return fmt.Errorf("KEY_POOL_ENABLED requires a random or sequence link generator, hashed links depend on the URL")
+		}
+		if c.KeyPool.LeaseSize <= 0 || c.KeyPool.LowWater < 0 || c.KeyPool.RefillSize <= 0 || c.KeyPool.CheckIntervalMs <= 0 {
+			return fmt.Errorf("KEY_POOL_LEASE_SIZE, KEY_POOL_REFILL_SIZE and KEY_POOL_CHECK_INTERVAL_MS must be positive and KEY_POOL_LOW_WATER non-negative")
+		}
+	}
+	if c.App.BatchMaxSize <= 0 || c.App.BatchMaxSize > maxBatchSize {
+		return fmt.Errorf("APP_BATCH_MAX_SIZE must be within [1, %d], got %d", maxBatchSize, c.App.BatchMaxSize)
+	}
+	if c.Analytics.BufferSize <= 0 || c.Analytics.FlushIntervalMs <= 0 {
+		return fmt.Errorf("ANALYTICS_BUFFER_SIZE and ANALYTICS_FLUSH_INTERVAL_MS must be positive")
+	}
+	if c.Analytics.BatchSize <= 0 || c.Analytics.BatchSize > maxClicksBatchSize {
+		return fmt.Errorf("ANALYTICS_BATCH_SIZE must be within [1, %d], got %d", maxClicksBatchSize, c.Analytics.BatchSize)
+	}
+	return nil
+}
+
+// ValidateStorage checks the settings that depend on the storage type, which is chosen on
+// the command line rather than in the environment.
+func (c *Config) ValidateStorage(storageType string) error {
+	// The memory counter starts over on restart and would hand out the IDs of stored links
+	// again, so it is only usable while the links are lost on restart as well.
+	persistent := storageType != "memory" || c.Memory.DataDir != ""
+	if c.App.LinkGenerator == "sequence" && c.App.LinkCounter == "memory" && persistent {
+		return fmt.Errorf("APP_LINK_COUNTER=memory restarts from zero and would reissue stored links, use postgres or redis with %s storage", storageType)
+	}
+	return nil
+}
+
+// validate checks the Postgres settings, they are only used with the postgres storage
+// but are checked regardless to fail early.
+func (d *DatabaseConfig) validate() error {
+	if d.DSN != "" {
+		if _, err := pq.NewConnector(d.DSN); err != nil {
+			return fmt.Errorf("invalid POSTGRES_DSN: %w", err)
+		}
+	}
+	switch d.SSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("POSTGRES_SSLMODE must be one of disable, require, verify-ca, verify-full, got %q", d.SSLMode)
+	}
+	if (d.SSLCert == "") != (d.SSLKey == "") {
+		return fmt.Errorf("POSTGRES_SSLCERT and POSTGRES_SSLKEY must be set together")
+	}
+	for name, path := range map[string]string{"POSTGRES_SSLROOTCERT": d.SSLRootCert, "POSTGRES_SSLCERT": d.SSLCert, "POSTGRES_SSLKEY": d.SSLKey} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 || d.ConnMaxLifetimeMs < 0 || d.ConnMaxIdleTimeMs < 0 || d.StatementTimeoutMs < 0 {
+		return fmt.Errorf("POSTGRES_MAX_OPEN_CONNS, POSTGRES_MAX_IDLE_CONNS, POSTGRES_CONN_MAX_LIFETIME_MS, POSTGRES_CONN_MAX_IDLE_TIME_MS and POSTGRES_STATEMENT_TIMEOUT_MS must not be negative")
+	}
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		return fmt.Errorf("POSTGRES_MAX_IDLE_CONNS must not exceed POSTGRES_MAX_OPEN_CONNS, got %d > %d", d.MaxIdleConns, d.MaxOpenConns)
+	}
+	if d.Schema != "" && !sqlIdentifier.MatchString(d.Schema) {
+		return fmt.Errorf("POSTGRES_SCHEMA must be a lowercase SQL identifier, got %q", d.Schema)
+	}
+	tables := map[string]string{"POSTGRES_LINKS_TABLE": d.LinksTable, "POSTGRES_CLICKS_TABLE": d.ClicksTable, "POSTGRES_KEYS_TABLE": d.KeysTable}
+	for name, table := range tables {
+		if !sqlIdentifier.MatchString(table) {
+			return fmt.Errorf("%s must be a lowercase SQL identifier, got %q", name, table)
+		}
+	}
+	if d.LinksTable == d.ClicksTable || d.LinksTable == d.KeysTable || d.ClicksTable == d.KeysTable {
+		return fmt.Errorf("POSTGRES_LINKS_TABLE, POSTGRES_CLICKS_TABLE and POSTGRES_KEYS_TABLE must differ")
+	}
+	return nil
 }
 
 // getEnv returns the value of an environment variable or a fallback value if it's not set.
@@ -87,3 +427,37 @@ func getEnvAsInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+// getEnvAsFloat returns the value of an environment variable as a float or a fallback value if it's not set or invalid.
+func getEnvAsFloat(key string, fallback float64) float64 {
+	valueStr := os.Getenv(key)
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// getEnvAsBool returns the value of an environment variable as a boolean or a fallback value if it's not set or invalid.
+func getEnvAsBool(key string, fallback bool) bool {
+	valueStr := os.Getenv(key)
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// getEnvAsSlice returns the comma-separated value of an environment variable as a slice or a fallback value if it's not set.
+func getEnvAsSlice(key string, fallback []string) []string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return fallback
+	}
+
+	var values []string
+	for _, v := range strings.Split(valueStr, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// Click is a single resolution of a short link.
+type Click struct {
+	ShortLink string    `yaml:"hash_id" json:"hash_id"`
+	At        time.Time `yaml:"at" json:"at"`
+	Referrer  string    `yaml:"referrer" json:"referrer"`
+	UserAgent string    `yaml:"user_agent" json:"user_agent"`
+	IP        string    `yaml:"ip" json:"ip"`
+}
+
+// ClickBucket holds the number of clicks in the interval starting at Start.
+type ClickBucket struct {
+	Start time.Time `yaml:"start" json:"start"`
+	Count int64     `yaml:"count" json:"count"`
+}
@@ -1,6 +1,15 @@
 package domain
 
+import "time"
+
 type Link struct {
-	ShortLink   string `yaml:"hash_id" json:"hash_id"`
-	OriginalURL string `yaml:"original_url" json:"original_url"`
+	ShortLink   string     `yaml:"hash_id" json:"hash_id"`
+	OriginalURL string     `yaml:"original_url" json:"original_url"`
+	ExpiresAt   *time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+	Disabled    bool       `yaml:"disabled" json:"disabled"`
+}
+
+// Expired reports whether the link has a deadline that is not after now.
+func (l *Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
 }
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/health"
+	resp "url-shortener/internal/lib/api/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler answers liveness and readiness probes.
+type HealthHandler struct {
+	log      *slog.Logger
+	registry *health.Registry
+}
+
+// NewHealthHandler creates a new HealthHandler instance.
+func NewHealthHandler(log *slog.Logger, registry *health.Registry) *HealthHandler {
+	return &HealthHandler{log: log, registry: registry}
+}
+
+// CheckResponse represents the state of a single dependency.
+type CheckResponse struct {
+	resp.Response
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// ReadinessResponse represents the state of the service and each of its dependencies.
+type ReadinessResponse struct {
+	resp.Response
+	Checks map[string]CheckResponse `json:"checks"`
+}
+
+// Liveness reports that the process is running and able to answer requests.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, resp.OK())
+}
+
+// Readiness runs the registered dependency checks and answers 503 if any of them fails.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	const op = "handlers.health.Readiness"
+
+	healthy, results := h.registry.Check(c.Request.Context())
+
+	checks := make(map[string]CheckResponse, len(results))
+	for name, res := range results {
+		check := CheckResponse{Response: resp.OK(), LatencyMs: float64(res.Latency.Microseconds()) / 1000}
+		if !res.Healthy {
+			check.Response = resp.Error(res.Error)
+		}
+		checks[name] = check
+	}
+
+	if !healthy {
+		h.log.Warn("service is not ready",
+			slog.String("op", op),
+			slog.Any("checks", checks),
+		)
+		c.JSON(http.StatusServiceUnavailable, ReadinessResponse{
+			Response: resp.Error("service is not ready"),
+			Checks:   checks,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ReadinessResponse{
+		Response: resp.OK(),
+		Checks:   checks,
+	})
+}
@@ -0,0 +1,119 @@
+package redirect
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/lib/ipmask"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const notFoundPage = `<!DOCTYPE html>
+<html>
+<head><title>404 Not Found</title></head>
+<body>
+<h1>404 Not Found</h1>
+<p>The short link you followed does not exist.</p>
+</body>
+</html>
+`
+
+const gonePage = `<!DOCTYPE html>
+<html>
+<head><title>410 Gone</title></head>
+<body>
+<h1>410 Gone</h1>
+<p>The short link you followed has expired.</p>
+</body>
+</html>
+`
+
+const disabledPage = `<!DOCTYPE html>
+<html>
+<head><title>403 Forbidden</title></head>
+<body>
+<h1>403 Forbidden</h1>
+<p>The short link you followed has been disabled.</p>
+</body>
+</html>
+`
+
+const errorPage = `<!DOCTYPE html>
+<html>
+<head><title>500 Internal Server Error</title></head>
+<body>
+<h1>500 Internal Server Error</h1>
+<p>The short link could not be resolved, please try again later.</p>
+</body>
+</html>
+`
+
+// RedirectHandler resolves short codes and redirects clients to the original URL.
+type RedirectHandler struct {
+	log       *slog.Logger
+	service   services.Shortener
+	analytics *services.AnalyticsService
+	code      int
+}
+
+// NewRedirectHandler creates a new RedirectHandler instance that answers with the given redirect status code.
+// Every GET redirect is recorded as a click.
+func NewRedirectHandler(log *slog.Logger, service services.Shortener, analytics *services.AnalyticsService, code int) *RedirectHandler {
+	return &RedirectHandler{log: log, service: service, analytics: analytics, code: code}
+}
+
+// Redirect sends the client to the original URL behind the short code.
+// Unknown and malformed codes are answered with an HTML 404 page, expired ones with 410
+// and disabled ones with 403.
+func (h *RedirectHandler) Redirect(c *gin.Context) {
+	const op = "handlers.redirect.Redirect"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", c.GetString("request_id")),
+	)
+
+	code := c.Param("code")
+
+	originalURL, err := h.service.GetOriginalURL(c.Request.Context(), code)
+	if errors.Is(err, services.ErrNotFound) || errors.Is(err, services.ErrInvalidLink) {
+		log.Info("short link was not found", slog.String("shortURL", code))
+		c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte(notFoundPage))
+		return
+	}
+	if errors.Is(err, services.ErrExpired) {
+		log.Info("short link has expired", slog.String("shortURL", code))
+		c.Data(http.StatusGone, "text/html; charset=utf-8", []byte(gonePage))
+		return
+	}
+	if errors.Is(err, services.ErrDisabled) {
+		log.Info("short link is disabled", slog.String("shortURL", code))
+		c.Data(http.StatusForbidden, "text/html; charset=utf-8", []byte(disabledPage))
+		return
+	}
+	if err != nil {
+		log.Error("failed to resolve short link", sl.Err(err))
+		c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(errorPage))
+		return
+	}
+
+	log.Info("redirecting", slog.String("shortURL", code), slog.String("originalURL", originalURL))
+
+	if c.Request.Method == http.MethodGet {
+		h.analytics.Record(domain.Click{
+			ShortLink: code,
+			At:        time.Now().UTC(),
+			Referrer:  c.Request.Referer(),
+			UserAgent: c.Request.UserAgent(),
+			IP:        ipmask.Truncate(c.ClientIP()),
+		})
+	}
+
+	c.Header("Cache-Control", "private, max-age=90")
+	c.Redirect(h.code, originalURL)
+}
@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/domain"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultStatsRange = 7 * 24 * time.Hour
+
+var bucketWidths = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// StatsHandler serves click statistics of short links.
+type StatsHandler struct {
+	log     *slog.Logger
+	service *services.AnalyticsService
+}
+
+// NewStatsHandler creates a new StatsHandler instance.
+func NewStatsHandler(log *slog.Logger, service *services.AnalyticsService) *StatsHandler {
+	return &StatsHandler{log: log, service: service}
+}
+
+// StatsResponse represents the click statistics of a short URL.
+type StatsResponse struct {
+	resp.Response
+	Total   int64                `json:"total"`
+	Bucket  string               `json:"bucket,omitempty"`
+	Buckets []domain.ClickBucket `json:"buckets,omitempty"`
+}
+
+// GetStats returns the number of clicks on a short URL as a histogram.
+//
+//	@Summary		Retrieve click statistics
+//	@Description	Returns the total number of clicks on the short URL in [from, to) and a histogram with one entry per bucket. The range defaults to the last 7 days.
+//	@Tags			stats
+//	@Produce		json
+//	@Param			link	path		string	true	"Short URL"
+//	@Param			from	query		string	false	"Range start, RFC 3339"
+//	@Param			to		query		string	false	"Range end, RFC 3339"
+//	@Param			bucket	query		string	false	"Bucket width"	Enums(minute, hour, day)	default(day)
+//	@Success		200		{object}	StatsResponse
+//	@Failure		400		{object}	resp.Response
+//	@Failure		404		{object}	resp.Response
+//	@Failure		500		{object}	resp.Response
+//	@Router			/link/{link}/stats [get]
+func (h *StatsHandler) GetStats(c *gin.Context) {
+	const op = "handlers.stats.GetStats"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", c.GetString("request_id")),
+	)
+
+	shortUrl := c.Param("link")
+
+	bucketName := c.DefaultQuery("bucket", "day")
+	bucket, ok := bucketWidths[bucketName]
+	if !ok {
+		log.Info("passed incorrect bucket", slog.String("bucket", bucketName))
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "bucket must be one of minute, hour, day",
+		},
+		)
+		return
+	}
+
+	to, err := parseTime(c.Query("to"), time.Now())
+	if err != nil {
+		log.Info("passed incorrect range end", sl.Err(err))
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "to must be an RFC 3339 timestamp",
+		},
+		)
+		return
+	}
+	from, err := parseTime(c.Query("from"), to.Add(-defaultStatsRange))
+	if err != nil {
+		log.Info("passed incorrect range start", sl.Err(err))
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "from must be an RFC 3339 timestamp",
+		},
+		)
+		return
+	}
+
+	stats, err := h.service.Stats(c.Request.Context(), shortUrl, from, to, bucket)
+	if errors.Is(err, services.ErrNotFound) {
+		log.Info("url was not found", slog.String("shortURL", shortUrl))
+		c.JSON(http.StatusNotFound, resp.NotFound("url was not found"))
+		return
+	}
+	if errors.Is(err, services.ErrInvalidStatsRange) {
+		log.Info("passed incorrect range", slog.Time("from", from), slog.Time("to", to))
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "from must be before to and the range must not exceed 1000 buckets",
+		},
+		)
+		return
+	}
+	if err != nil {
+		log.Error("failed to get stats", sl.Err(err))
+		c.JSON(http.StatusInternalServerError, resp.InternalError("failed to get stats"))
+		return
+	}
+
+	c.JSON(http.StatusOK, StatsResponse{
+		Response: resp.OK(),
+		Total:    stats.Total,
+		Bucket:   bucketName,
+		Buckets:  stats.Buckets,
+	})
+}
+
+// parseTime parses an RFC 3339 timestamp, returning fallback for an empty value.
+func parseTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
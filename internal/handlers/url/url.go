@@ -1,12 +1,15 @@
 package url
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/services"
@@ -17,13 +20,14 @@ import (
 
 // LinksHandler handles URL shortening and retrieval operations.
 type LinksHandler struct {
-	log     *slog.Logger
-	service *services.LinkService
+	log          *slog.Logger
+	service      services.Shortener
+	maxBatchSize int
 }
 
 // NewLinkHandler creates a new LinksHandler instance.
-func NewLinkHandler(log *slog.Logger, service *services.LinkService) *LinksHandler {
-	return &LinksHandler{log: log, service: service}
+func NewLinkHandler(log *slog.Logger, service services.Shortener, maxBatchSize int) *LinksHandler {
+	return &LinksHandler{log: log, service: service, maxBatchSize: maxBatchSize}
 }
 
 // GetRequest represents a request to retrieve the original URL.
@@ -37,6 +41,7 @@ type GetResponse struct {
 }
 
 // GetLink retrieves the original URL for a given short URL.
+//
 //	@Summary		Retrieve the original URL
 //	@Description	Retrieves the original URL associated with the provided short URL.
 //	@Tags			url
@@ -45,9 +50,11 @@ type GetResponse struct {
 //	@Param			link	path		string	true	"Short URL"
 //	@Success		200		{object}	GetResponse
 //	@Failure		400		{object}	resp.Response
+//	@Failure		403		{object}	resp.Response
 //	@Failure		404		{object}	resp.Response
+//	@Failure		410		{object}	resp.Response
 //	@Failure		500		{object}	resp.Response
-//	@Router			/{link} [get]
+//	@Router			/link/{link} [get]
 func (h *LinksHandler) GetLink(c *gin.Context) {
 	const op = "handlers.url.GetLink"
 
@@ -58,12 +65,22 @@ func (h *LinksHandler) GetLink(c *gin.Context) {
 
 	shortUrl := c.Param("link")
 
-	originalURL, err := h.service.GetOriginalURL(shortUrl)
+	originalURL, err := h.service.GetOriginalURL(c.Request.Context(), shortUrl)
 	if errors.Is(err, services.ErrNotFound) {
 		log.Info("url was not found", slog.String("shortURL", shortUrl))
 		c.JSON(http.StatusNotFound, resp.NotFound("url was not found"))
 		return
 	}
+	if errors.Is(err, services.ErrExpired) {
+		log.Info("url has expired", slog.String("shortURL", shortUrl))
+		c.JSON(http.StatusGone, resp.Gone("url has expired"))
+		return
+	}
+	if errors.Is(err, services.ErrDisabled) {
+		log.Info("url is disabled", slog.String("shortURL", shortUrl))
+		c.JSON(http.StatusForbidden, resp.Forbidden("url is disabled"))
+		return
+	}
 	if errors.Is(err, services.ErrInvalidLink) {
 		log.Info("passed incorrect link", slog.String("shortURL", shortUrl))
 		c.JSON(http.StatusBadRequest, resp.Response{
@@ -89,7 +106,11 @@ func (h *LinksHandler) GetLink(c *gin.Context) {
 
 // SaveRequest represents a request to save a new short URL.
 type SaveRequest struct {
-	OriginalURL string `json:"url" validate:"required,url"`
+	OriginalURL string     `json:"url" validate:"required,url"`
+	Alias       string     `json:"alias,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	TTLSeconds  int64      `json:"ttl_seconds,omitempty" validate:"omitempty,min=1,max=3153600000"` // At most 100 years
+	Style       string     `json:"style,omitempty"`
 }
 
 // SaveResponse represents the response for saving a new short URL.
@@ -99,16 +120,18 @@ type SaveResponse struct {
 }
 
 // SaveLink saves a new short URL for the provided original URL.
+//
 //	@Summary		Save a new short URL
-//	@Description	Saves a new short URL for the provided original URL.
+//	@Description	Saves a new short URL for the provided original URL. An optional alias is used as the short link instead of a generated one, an optional style picks how it is generated (e.g. "words" for brave-otter-42). The link expires at expires_at or after ttl_seconds (at most 100 years) when either is given, a URL that is already shortened with another expiration is a conflict. As ttl_seconds is counted from the request, resubmitting it always conflicts, pass expires_at for idempotent retries.
 //	@Tags			url
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		SaveRequest	true	"Original URL to shorten"
 //	@Success		200		{object}	SaveResponse
 //	@Failure		400		{object}	resp.Response
+//	@Failure		409		{object}	resp.Response
 //	@Failure		500		{object}	resp.Response
-//	@Router			/ [post]
+//	@Router			/link/ [post]
 func (h *LinksHandler) SaveLink(c *gin.Context) {
 	const op = "handlers.url.SaveLink"
 
@@ -148,7 +171,24 @@ func (h *LinksHandler) SaveLink(c *gin.Context) {
 		return
 	}
 
-	shortURL, err := h.service.Save(req.OriginalURL, 5)
+	if req.ExpiresAt != nil && req.TTLSeconds != 0 {
+		log.Info("both expires_at and ttl_seconds passed")
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "Pass either expires_at or ttl_seconds, not both",
+		},
+		)
+		return
+	}
+
+	opts := services.SaveOptions{Alias: req.Alias, ExpiresAt: req.ExpiresAt, Style: req.Style}
+	// The TTL becomes a deadline now, so resubmitting it for a shortened URL is a conflict.
+	if req.TTLSeconds != 0 {
+		expiresAt := time.Now().Add(time.Duration(req.TTLSeconds) * time.Second)
+		opts.ExpiresAt = &expiresAt
+	}
+
+	shortURL, err := h.service.Save(c.Request.Context(), req.OriginalURL, opts, 5)
 	if errors.Is(err, services.ErrInvalidURL) {
 		log.Info("passed incorrect link", slog.String("originalURL", req.OriginalURL))
 		c.JSON(http.StatusBadRequest, resp.Response{
@@ -158,6 +198,38 @@ func (h *LinksHandler) SaveLink(c *gin.Context) {
 		)
 		return
 	}
+	if errors.Is(err, services.ErrInvalidExpiry) {
+		log.Info("passed expiration in the past")
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "Passed invalid expiration: " + err.Error(),
+		},
+		)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidStyle) {
+		log.Info("passed unknown style", slog.String("style", req.Style))
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "Passed unknown style: " + req.Style,
+		},
+		)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidAlias) || errors.Is(err, services.ErrReservedAlias) {
+		log.Info("passed incorrect alias", slog.String("alias", req.Alias), sl.Err(err))
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "Passed invalid alias: " + err.Error(),
+		},
+		)
+		return
+	}
+	if errors.Is(err, services.ErrAliasTaken) || errors.Is(err, services.ErrURLExists) || errors.Is(err, services.ErrExpiryConflict) {
+		log.Info("alias conflict", slog.String("alias", req.Alias), sl.Err(err))
+		c.JSON(http.StatusConflict, resp.Conflict(err.Error()))
+		return
+	}
 	if errors.Is(err, services.ErrMaxRetriesExceeded) {
 		log.Info("max retries exceeded, could not save", slog.String("originalURL", req.OriginalURL))
 		c.JSON(http.StatusInternalServerError, resp.InternalError("max retries exceeded"))
@@ -176,3 +248,199 @@ func (h *LinksHandler) SaveLink(c *gin.Context) {
 		ShortURL: shortURL,
 	})
 }
+
+// BatchRequest represents a request to shorten several URLs at once.
+type BatchRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// BatchItem represents the outcome for a single URL of a batch.
+type BatchItem struct {
+	resp.Response
+	OriginalURL string `json:"url"`
+	ShortURL    string `json:"link,omitempty"`
+}
+
+// BatchResponse represents the response for a batch of URLs, in request order.
+type BatchResponse struct {
+	resp.Response
+	Results []BatchItem `json:"results,omitempty"`
+}
+
+// SaveBatch saves short URLs for several original URLs at once.
+//
+//	@Summary		Save a batch of short URLs
+//	@Description	Saves short URLs for all provided original URLs. Every item gets its own result in request order, failed items do not abort the batch.
+//	@Tags			url
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		BatchRequest	true	"Original URLs to shorten"
+//	@Success		200		{object}	BatchResponse
+//	@Failure		400		{object}	resp.Response
+//	@Failure		500		{object}	resp.Response
+//	@Router			/link/batch [post]
+func (h *LinksHandler) SaveBatch(c *gin.Context) {
+	const op = "handlers.url.SaveBatch"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", c.GetString("request_id")),
+	)
+
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			c.JSON(http.StatusBadRequest, resp.Error("empty request"))
+			return
+		}
+
+		log.Error("failed to decode request body", sl.Err(err))
+		c.JSON(http.StatusBadRequest, resp.Error("failed to decode request"))
+		return
+	}
+
+	if len(req.URLs) == 0 {
+		log.Error("empty batch")
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "field urls is a required field",
+		},
+		)
+		return
+	}
+	if len(req.URLs) > h.maxBatchSize {
+		log.Info("batch is too large", slog.Int("size", len(req.URLs)))
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  fmt.Sprintf("batch must not contain more than %d urls", h.maxBatchSize),
+		},
+		)
+		return
+	}
+
+	results, err := h.service.SaveBatch(c.Request.Context(), req.URLs, 5)
+	if err != nil {
+		log.Error("failed to add urls", sl.Err(err))
+		c.JSON(http.StatusInternalServerError, resp.InternalError("failed to add urls"))
+		return
+	}
+
+	items := make([]BatchItem, len(results))
+	for i, res := range results {
+		items[i] = BatchItem{Response: resp.OK(), OriginalURL: req.URLs[i], ShortURL: res.ShortURL}
+		switch {
+		case res.Err == nil:
+		case errors.Is(res.Err, services.ErrInvalidURL):
+			items[i].Response = resp.Response{Status: resp.StatusBadRequest, Error: "invalid url"}
+		case errors.Is(res.Err, services.ErrMaxRetriesExceeded):
+			items[i].Response = resp.InternalError("max retries exceeded")
+		default:
+			log.Error("failed to add url", slog.String("originalURL", req.URLs[i]), sl.Err(res.Err))
+			items[i].Response = resp.InternalError("failed to add url")
+		}
+	}
+
+	log.Info("batch processed", slog.Int("size", len(items)))
+
+	c.JSON(http.StatusOK, BatchResponse{
+		Response: resp.OK(),
+		Results:  items,
+	})
+}
+
+// DeleteLink removes the short URL so it no longer resolves.
+//
+//	@Summary		Delete a short URL
+//	@Description	Deletes the short URL, its original URL can be shortened again afterwards.
+//	@Tags			url
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			link	path		string	true	"Short URL"
+//	@Success		200		{object}	resp.Response
+//	@Failure		400		{object}	resp.Response
+//	@Failure		401		{object}	resp.Response
+//	@Failure		403		{object}	resp.Response
+//	@Failure		404		{object}	resp.Response
+//	@Failure		500		{object}	resp.Response
+//	@Router			/link/{link} [delete]
+func (h *LinksHandler) DeleteLink(c *gin.Context) {
+	h.updateLink(c, "handlers.url.DeleteLink", h.service.Delete)
+}
+
+// DisableLink keeps the short URL stored but stops it from resolving.
+//
+//	@Summary		Disable a short URL
+//	@Description	Disables the short URL, it is kept but no longer redirects.
+//	@Tags			url
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			link	path		string	true	"Short URL"
+//	@Success		200		{object}	resp.Response
+//	@Failure		400		{object}	resp.Response
+//	@Failure		401		{object}	resp.Response
+//	@Failure		403		{object}	resp.Response
+//	@Failure		404		{object}	resp.Response
+//	@Failure		500		{object}	resp.Response
+//	@Router			/link/{link}/disable [post]
+func (h *LinksHandler) DisableLink(c *gin.Context) {
+	h.updateLink(c, "handlers.url.DisableLink", func(ctx context.Context, shortLink string) error {
+		return h.service.SetDisabled(ctx, shortLink, true)
+	})
+}
+
+// EnableLink makes a disabled short URL resolve again.
+//
+//	@Summary		Enable a short URL
+//	@Description	Re-enables a previously disabled short URL.
+//	@Tags			url
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			link	path		string	true	"Short URL"
+//	@Success		200		{object}	resp.Response
+//	@Failure		400		{object}	resp.Response
+//	@Failure		401		{object}	resp.Response
+//	@Failure		403		{object}	resp.Response
+//	@Failure		404		{object}	resp.Response
+//	@Failure		500		{object}	resp.Response
+//	@Router			/link/{link}/enable [post]
+func (h *LinksHandler) EnableLink(c *gin.Context) {
+	h.updateLink(c, "handlers.url.EnableLink", func(ctx context.Context, shortLink string) error {
+		return h.service.SetDisabled(ctx, shortLink, false)
+	})
+}
+
+// updateLink applies a state change to the short URL from the path and writes the outcome.
+func (h *LinksHandler) updateLink(c *gin.Context, op string, update func(context.Context, string) error) {
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", c.GetString("request_id")),
+	)
+
+	shortUrl := c.Param("link")
+
+	err := update(c.Request.Context(), shortUrl)
+	if errors.Is(err, services.ErrNotFound) {
+		log.Info("url was not found", slog.String("shortURL", shortUrl))
+		c.JSON(http.StatusNotFound, resp.NotFound("url was not found"))
+		return
+	}
+	if errors.Is(err, services.ErrInvalidLink) {
+		log.Info("passed incorrect link", slog.String("shortURL", shortUrl))
+		c.JSON(http.StatusBadRequest, resp.Response{
+			Status: resp.StatusBadRequest,
+			Error:  "Passed invalid short link",
+		},
+		)
+		return
+	}
+	if err != nil {
+		log.Error("failed to update url", sl.Err(err))
+		c.JSON(http.StatusInternalServerError, resp.InternalError("failed to update url"))
+		return
+	}
+
+	log.Info("url updated", slog.String("shortURL", shortUrl))
+
+	c.JSON(http.StatusOK, resp.OK())
+}
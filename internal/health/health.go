@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Checker is implemented by dependencies that can report whether they are able to serve requests.
+type Checker interface {
+	HealthCheck(context.Context) error
+}
+
+// Result is the outcome of a single dependency check.
+type Result struct {
+	Healthy bool
+	Error   string
+	Latency time.Duration
+}
+
+// Registry runs the health checks registered by the application dependencies.
+type Registry struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers map[string]Checker
+}
+
+// NewRegistry creates a Registry that bounds every check by timeout.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout, checkers: make(map[string]Checker)}
+}
+
+// Register adds a named check, replacing any check registered under the same name.
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkers[name] = checker
+}
+
+// Check runs all registered checks concurrently and reports whether every one of them passed.
+func (r *Registry) Check(ctx context.Context) (bool, map[string]Result) {
+	r.mu.RLock()
+	checkers := make(map[string]Checker, len(r.checkers))
+	for name, checker := range r.checkers {
+		checkers[name] = checker
+	}
+	r.mu.RUnlock()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		healthy = true
+		results = make(map[string]Result, len(checkers))
+	)
+	for name, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := checker.HealthCheck(ctx)
+			res := Result{Healthy: err == nil, Latency: time.Since(start)}
+			if err != nil {
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = res
+			healthy = healthy && res.Healthy
+		}()
+	}
+	wg.Wait()
+
+	return healthy, results
+}
@@ -15,10 +15,14 @@ type Response struct {
 }
 
 const (
-	StatusOK         = "OK"
-	StatusError      = "Error"
-	StatusBadRequest = "BadRequest"
-	StatusNotFound   = "NotFound"
+	StatusOK           = "OK"
+	StatusError        = "Error"
+	StatusBadRequest   = "BadRequest"
+	StatusNotFound     = "NotFound"
+	StatusConflict     = "Conflict"
+	StatusGone         = "Gone"
+	StatusForbidden    = "Forbidden"
+	StatusUnauthorized = "Unauthorized"
 )
 
 // OK creates a success response.
@@ -64,6 +68,38 @@ func NotFound(msg string) Response {
 	}
 }
 
+// Unauthorized creates a response for requests lacking valid credentials.
+func Unauthorized(msg string) Response {
+	return Response{
+		Status: StatusUnauthorized,
+		Error:  msg,
+	}
+}
+
+// Forbidden creates a response for resources that exist but may not be served.
+func Forbidden(msg string) Response {
+	return Response{
+		Status: StatusForbidden,
+		Error:  msg,
+	}
+}
+
+// Gone creates a response for resources that existed but are no longer available.
+func Gone(msg string) Response {
+	return Response{
+		Status: StatusGone,
+		Error:  msg,
+	}
+}
+
+// Conflict creates a response for requests that clash with existing data.
+func Conflict(msg string) Response {
+	return Response{
+		Status: StatusConflict,
+		Error:  msg,
+	}
+}
+
 // InternalError creates a response for internal server errors.
 func InternalError(msg string) Response {
 	return Response{
@@ -0,0 +1,27 @@
+package generator
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Counter is a source of unique, increasing IDs shared by every instance that uses it.
+type Counter interface {
+	Next(context.Context) (uint64, error)
+}
+
+// AtomicCounter counts in process memory, it only suits a single instance without persistent storage.
+type AtomicCounter struct {
+	value atomic.Uint64
+}
+
+// NewAtomicCounter creates a counter whose first ID is start+1.
+func NewAtomicCounter(start uint64) *AtomicCounter {
+	c := &AtomicCounter{}
+	c.value.Store(start)
+	return c
+}
+
+func (c *AtomicCounter) Next(context.Context) (uint64, error) {
+	return c.value.Add(1), nil
+}
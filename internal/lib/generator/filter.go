@@ -0,0 +1,187 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxRejections bounds how many short links the filter rejects before giving up on a request.
+const maxRejections = 16
+
+var ErrFilterExhausted = errors.New("every generated short link was rejected by the filter")
+
+// lookAlikes groups characters that are easily confused, the first one of a group present
+// in the alphabet is the canonical form of the whole group.
+var lookAlikes = []string{"0Oo", "1lI"}
+
+// leetReplacer undoes common digit substitutions, so that blocked words spelled with digits are caught too.
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "_", "")
+
+// UnambiguousAlphabet removes from the alphabet every look-alike character except the canonical one of its group.
+func UnambiguousAlphabet(alphabet string) string {
+	canonical := canonicalRunes(alphabet)
+	var b strings.Builder
+	for _, ch := range alphabet {
+		if to, ok := canonical[ch]; !ok || to == ch {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+// canonicalRunes maps every look-alike character of the alphabet's groups to the canonical one.
+func canonicalRunes(alphabet string) map[rune]rune {
+	canonical := make(map[rune]rune)
+	for _, group := range lookAlikes {
+		idx := strings.IndexFunc(group, func(ch rune) bool { return strings.ContainsRune(alphabet, ch) })
+		if idx < 0 {
+			continue
+		}
+		to := rune(group[idx])
+		for _, ch := range group {
+			canonical[ch] = to
+		}
+	}
+	return canonical
+}
+
+// FilteredGenerator wraps a generator and regenerates short links that contain a blocked word.
+// In unambiguous mode it also rejects short links with look-alike characters and maps them to
+// their canonical form when resolving.
+type FilteredGenerator struct {
+	next      Generator
+	blocklist []string
+	canonical map[rune]rune
+}
+
+// NewFilteredGenerator creates a filter around next. The blocked words are matched case-insensitively
+// anywhere in the short link. Canonical forms are taken from alphabet when unambiguous is set, next
+// should then generate over UnambiguousAlphabet(alphabet) to avoid rejections.
+func NewFilteredGenerator(next Generator, alphabet string, blocklist []string, unambiguous bool) *FilteredGenerator {
+	g := &FilteredGenerator{next: next}
+	for _, word := range blocklist {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			g.blocklist = append(g.blocklist, word)
+		}
+	}
+	if unambiguous {
+		g.canonical = canonicalRunes(alphabet)
+	}
+	return g
+}
+
+// Generate asks the wrapped generator for short links until one passes the filter. Rejected
+// attempts are numbered after the request's own attempt, so deterministic generators move on.
+func (g *FilteredGenerator) Generate(ctx context.Context, req Request) (string, error) {
+	for i := 0; i < maxRejections; i++ {
+		attempt := req
+		attempt.Attempt = req.Attempt*maxRejections + i
+
+		code, err := g.next.Generate(ctx, attempt)
+		if err != nil {
+			return "", err
+		}
+		if g.allowed(code) {
+			return code, nil
+		}
+	}
+	return "", ErrFilterExhausted
+}
+
+func (g *FilteredGenerator) allowed(code string) bool {
+	if g.canonical != nil && g.Canonical(code) != code {
+		return false
+	}
+
+	lower := strings.ToLower(code)
+	unleeted := leetReplacer.Replace(lower)
+	for _, word := range g.blocklist {
+		if strings.Contains(lower, word) || strings.Contains(unleeted, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Canonical maps the look-alike characters of the code to their canonical form.
+// It returns the code unchanged unless the unambiguous mode is on.
+func (g *FilteredGenerator) Canonical(code string) string {
+	if g.canonical == nil {
+		return code
+	}
+	return strings.Map(func(ch rune) rune {
+		if to, ok := g.canonical[ch]; ok {
+			return to
+		}
+		return ch
+	}, code)
+}
+
+// Unwrap returns the filtered generator.
+func (g *FilteredGenerator) Unwrap() Generator {
+	return g.next
+}
+
+func (g *FilteredGenerator) Valid(code string) bool {
+	v, ok := g.next.(Validator)
+	return ok && v.Valid(code)
+}
+
+func (g *FilteredGenerator) Deterministic() bool {
+	d, ok := g.next.(Deterministic)
+	return ok && d.Deterministic()
+}
+
+func (g *FilteredGenerator) ReportCollision() {
+	if r, ok := g.next.(CollisionReporter); ok {
+		r.ReportCollision()
+	}
+}
+
+func (g *FilteredGenerator) Stats() Stats {
+	if r, ok := g.next.(CollisionReporter); ok {
+		return r.Stats()
+	}
+	return Stats{}
+}
+
+// Canonicalizer is implemented by generators whose short links may be typed with look-alike characters.
+type Canonicalizer interface {
+	Canonical(string) string
+}
+
+// LoadBlocklist reads one blocked word per line, ignoring empty lines and lines starting with '#'.
+func LoadBlocklist(path string) ([]string, error) {
+	return readWords(path)
+}
+
+// LoadWordList reads a word list for the WordGenerator in the format of LoadBlocklist.
+func LoadWordList(path string) ([]string, error) {
+	return readWords(path)
+}
+
+func readWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read word list: %w", err)
+	}
+	return words, nil
+}
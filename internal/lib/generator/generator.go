@@ -1,32 +1,106 @@
 package generator
 
 import (
-	"time"
-
-	"math/rand"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
 )
 
+// ErrShortAlphabet is returned by the generators given an alphabet they cannot encode short links with.
+var ErrShortAlphabet = errors.New("alphabet must have at least 2 characters")
+
+// Request describes the short link to generate. Attempt counts the previous
+// attempts for the same URL that ended in a collision.
+type Request struct {
+	OriginalURL string
+	Size        int
+	Attempt     int
+}
+
 type Generator interface {
-	Generate(int) string
+	Generate(context.Context, Request) (string, error)
 }
 
+// Deterministic is implemented by generators that always produce the same short link
+// for the same request, so an existing link can be recognized without storing it again.
+type Deterministic interface {
+	Deterministic() bool
+}
+
+// RandomGenerator picks every character uniformly from the alphabet using crypto/rand,
+// so short links are unpredictable and concurrent calls never share a random state.
 type RandomGenerator struct {
 	alphabet []rune
+	// width is the number of random bytes drawn per character.
+	width int
+	// limit is the largest multiple of the alphabet size not exceeding the range of width bytes,
+	// samples at or above it are rejected to avoid a modulo bias.
+	limit uint64
+	stats CollisionStats
 }
 
-func NewRandomGenerator(alphabet string) *RandomGenerator {
-	return &RandomGenerator{alphabet: []rune(alphabet)}
+func NewRandomGenerator(alphabet string) (*RandomGenerator, error) {
+	runes := []rune(alphabet)
+	if len(runes) < 2 {
+		return nil, ErrShortAlphabet
+	}
+	g := &RandomGenerator{alphabet: runes}
+
+	switch n := uint64(len(runes)); {
+	case n <= math.MaxUint8+1:
+		g.width = 1
+	case n <= math.MaxUint16+1:
+		g.width = 2
+	default:
+		g.width = 4
+	}
+	space := uint64(1) << (8 * g.width)
+	g.limit = space - space%uint64(len(runes))
+
+	return g, nil
 }
 
-func (g *RandomGenerator) Generate(size int) string {
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+func (g *RandomGenerator) Generate(_ context.Context, req Request) (string, error) {
+	n := uint64(len(g.alphabet))
+	genRunes := make([]rune, 0, req.Size)
+
+	// Draw a bit more than needed up front, rejected samples are rare.
+	buf := make([]byte, g.width*(req.Size+req.Size/4+1))
+	for len(genRunes) < req.Size {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		for i := 0; i+g.width <= len(buf) && len(genRunes) < req.Size; i += g.width {
+			if sample := g.sample(buf[i : i+g.width]); sample < g.limit {
+				genRunes = append(genRunes, g.alphabet[sample%n])
+			}
+		}
+	}
+
+	g.stats.generated.Add(1)
+	return string(genRunes), nil
+}
 
-	genRunes := make([]rune, 0, size)
-	var rndChar rune
-	for len(genRunes) < size {
-		rndChar = g.alphabet[rnd.Intn(len(g.alphabet))]
-		genRunes = append(genRunes, rndChar)
+func (g *RandomGenerator) sample(b []byte) uint64 {
+	switch g.width {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b))
+	default:
+		return uint64(binary.BigEndian.Uint32(b))
 	}
+}
+
+// ReportCollision records that a generated short link was already taken.
+func (g *RandomGenerator) ReportCollision() {
+	g.stats.collisions.Add(1)
+}
 
-	return string(genRunes)
+// Stats returns the number of generated short links and reported collisions so far.
+func (g *RandomGenerator) Stats() Stats {
+	return g.stats.Snapshot()
 }
@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrEmptyHashKey = errors.New("hash generator key must not be empty")
+
+// HashGenerator derives the short link from an HMAC-SHA256 of the normalized URL,
+// so every instance sharing the key maps a URL to the same short link.
+// Collisions are resolved by salting the hash with the attempt number.
+type HashGenerator struct {
+	alphabet []rune
+	key      []byte
+}
+
+func NewHashGenerator(alphabet string, key []byte) (*HashGenerator, error) {
+	if utf8.RuneCountInString(alphabet) < 2 {
+		return nil, ErrShortAlphabet
+	}
+	if len(key) == 0 {
+		return nil, ErrEmptyHashKey
+	}
+	return &HashGenerator{alphabet: []rune(alphabet), key: key}, nil
+}
+
+func (g *HashGenerator) Deterministic() bool {
+	return true
+}
+
+func (g *HashGenerator) Generate(_ context.Context, req Request) (string, error) {
+	message := NormalizeURL(req.OriginalURL)
+	if req.Attempt > 0 {
+		message += "\x00" + strconv.Itoa(req.Attempt)
+	}
+
+	// Take 64 bits more than the short link holds, so the modulo bias of the encoding is negligible.
+	base := big.NewInt(int64(len(g.alphabet)))
+	bits := int(math.Ceil(float64(req.Size)*math.Log2(float64(len(g.alphabet))))) + 64
+	n := new(big.Int).SetBytes(g.digest(message, (bits+7)/8))
+
+	genRunes := make([]rune, req.Size)
+	rem := new(big.Int)
+	for i := range genRunes {
+		n.DivMod(n, base, rem)
+		genRunes[i] = g.alphabet[rem.Int64()]
+	}
+
+	return string(genRunes), nil
+}
+
+// digest returns size bytes of keyed hash, chaining HMAC blocks in counter mode when one is not enough.
+func (g *HashGenerator) digest(message string, size int) []byte {
+	out := make([]byte, 0, size+sha256.Size)
+	var counter [4]byte
+	for i := uint32(0); len(out) < size; i++ {
+		mac := hmac.New(sha256.New, g.key)
+		binary.BigEndian.PutUint32(counter[:], i)
+		mac.Write(counter[:])
+		mac.Write([]byte(message))
+		out = mac.Sum(out)
+	}
+	return out[:size]
+}
+
+// NormalizeURL returns the canonical form of the URL used for hashing: the scheme and host
+// are lower-cased, default ports are dropped and an empty path becomes "/".
+// Unparsable URLs are returned unchanged.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	if u.Path == "" && u.RawPath == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"unicode/utf8"
+)
+
+var (
+	ErrEmptySequenceKey  = errors.New("sequence generator key must not be empty")
+	ErrSequenceExhausted = errors.New("sequence exhausted for the short link size")
+)
+
+const (
+	feistelRounds = 4
+	// maxSequenceDomain bounds the permuted IDs, so that both Feistel halves fit in 31 bits.
+	maxSequenceDomain = uint64(1) << 62
+)
+
+// SequenceGenerator encodes IDs of a Counter in the alphabet. The IDs are shuffled by a keyed
+// Feistel permutation first, so consecutive links do not get similar short links. Since the
+// permutation is a bijection, two IDs never produce the same short link of a given size.
+type SequenceGenerator struct {
+	alphabet []rune
+	counter  Counter
+	key      []byte
+}
+
+func NewSequenceGenerator(alphabet string, counter Counter, key []byte) (*SequenceGenerator, error) {
+	if utf8.RuneCountInString(alphabet) < 2 {
+		return nil, ErrShortAlphabet
+	}
+	if len(key) == 0 {
+		return nil, ErrEmptySequenceKey
+	}
+	return &SequenceGenerator{alphabet: []rune(alphabet), counter: counter, key: key}, nil
+}
+
+func (g *SequenceGenerator) Generate(ctx context.Context, req Request) (string, error) {
+	id, err := g.counter.Next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get next id: %w", err)
+	}
+
+	base := uint64(len(g.alphabet))
+	domain := sequenceDomain(base, req.Size)
+	if id >= domain {
+		return "", ErrSequenceExhausted
+	}
+
+	n := g.permute(id, domain)
+	genRunes := make([]rune, req.Size)
+	for i := range genRunes {
+		genRunes[i] = g.alphabet[n%base]
+		n /= base
+	}
+
+	return string(genRunes), nil
+}
+
+// sequenceDomain returns the number of distinct short links of the given size, capped at maxSequenceDomain.
+func sequenceDomain(base uint64, size int) uint64 {
+	domain := uint64(1)
+	for i := 0; i < size; i++ {
+		if domain > maxSequenceDomain/base {
+			return maxSequenceDomain
+		}
+		domain *= base
+	}
+	return domain
+}
+
+// permute maps x in [0, domain) to another value in [0, domain). The Feistel network permutes
+// the smallest even-width power of two holding the domain, and values falling outside of the
+// domain are walked through the network again until they land inside it.
+func (g *SequenceGenerator) permute(x, domain uint64) uint64 {
+	width := bits.Len64(domain - 1)
+	width += width % 2
+	half := width / 2
+	mask := uint64(1)<<half - 1
+
+	for {
+		left, right := x>>half, x&mask
+		for round := 0; round < feistelRounds; round++ {
+			left, right = right, left^(g.round(round, right)&mask)
+		}
+		x = left<<half | right
+		if x < domain {
+			return x
+		}
+	}
+}
+
+func (g *SequenceGenerator) round(round int, value uint64) uint64 {
+	buf := make([]byte, 0, len(g.key)+9)
+	buf = append(buf, g.key...)
+	buf = append(buf, byte(round))
+	buf = binary.BigEndian.AppendUint64(buf, value)
+	sum := sha256.Sum256(buf)
+	return binary.BigEndian.Uint64(sum[:]) & math.MaxUint32
+}
@@ -0,0 +1,34 @@
+package generator
+
+import "sync/atomic"
+
+// CollisionReporter is implemented by generators that keep collision statistics.
+// The caller reports every generated short link that turned out to be taken.
+type CollisionReporter interface {
+	ReportCollision()
+	Stats() Stats
+}
+
+// Stats is a snapshot of collision statistics.
+type Stats struct {
+	Generated  uint64
+	Collisions uint64
+}
+
+// CollisionRate returns the share of generated short links that collided.
+func (s Stats) CollisionRate() float64 {
+	if s.Generated == 0 {
+		return 0
+	}
+	return float64(s.Collisions) / float64(s.Generated)
+}
+
+// CollisionStats counts generated short links and collisions, it is safe for concurrent use.
+type CollisionStats struct {
+	generated  atomic.Uint64
+	collisions atomic.Uint64
+}
+
+func (s *CollisionStats) Snapshot() Stats {
+	return Stats{Generated: s.generated.Load(), Collisions: s.collisions.Load()}
+}
@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+//go:embed words/adjectives.txt
+var defaultAdjectives string
+
+//go:embed words/nouns.txt
+var defaultNouns string
+
+var ErrInvalidWordList = errors.New("word lists must be non-empty and contain lowercase latin words only")
+
+// WordGenerator produces human-readable short links like "brave-otter-42": an adjective,
+// a noun and a zero-padded number of the configured digits, joined with '-'.
+// The size of the request is ignored.
+type WordGenerator struct {
+	adjectives  []string
+	nouns       []string
+	digits      int
+	maxAdjLen   int
+	maxNounLen  int
+	numberRange *big.Int
+}
+
+// DefaultWordLists returns the embedded adjectives and nouns.
+func DefaultWordLists() (adjectives, nouns []string) {
+	return strings.Fields(defaultAdjectives), strings.Fields(defaultNouns)
+}
+
+// LoadWordLists reads the adjectives and nouns from the given files, an empty path keeps
+// the embedded list.
+func LoadWordLists(adjectivesFile, nounsFile string) (adjectives, nouns []string, err error) {
+	adjectives, nouns = DefaultWordLists()
+	if adjectivesFile != "" {
+		if adjectives, err = LoadWordList(adjectivesFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	if nounsFile != "" {
+		if nouns, err = LoadWordList(nounsFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	return adjectives, nouns, nil
+}
+
+func NewWordGenerator(adjectives, nouns []string, digits int) (*WordGenerator, error) {
+	if digits < 0 || digits > 9 {
+		return nil, fmt.Errorf("invalid number of digits %d", digits)
+	}
+
+	maxAdjLen, ok := maxWordLength(adjectives)
+	if !ok {
+		return nil, ErrInvalidWordList
+	}
+	maxNounLen, ok := maxWordLength(nouns)
+	if !ok {
+		return nil, ErrInvalidWordList
+	}
+
+	numberRange := big.NewInt(1)
+	for i := 0; i < digits; i++ {
+		numberRange.Mul(numberRange, big.NewInt(10))
+	}
+
+	return &WordGenerator{
+		adjectives:  adjectives,
+		nouns:       nouns,
+		digits:      digits,
+		maxAdjLen:   maxAdjLen,
+		maxNounLen:  maxNounLen,
+		numberRange: numberRange,
+	}, nil
+}
+
+// maxWordLength returns the length of the longest word and whether every word is valid.
+func maxWordLength(words []string) (int, bool) {
+	longest := 0
+	for _, word := range words {
+		if !isLowerWord(word) {
+			return 0, false
+		}
+		longest = max(longest, len(word))
+	}
+	return longest, longest > 0
+}
+
+func isLowerWord(word string) bool {
+	if word == "" {
+		return false
+	}
+	for _, ch := range word {
+		if ch < 'a' || ch > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *WordGenerator) Generate(_ context.Context, _ Request) (string, error) {
+	adjective, err := g.pick(big.NewInt(int64(len(g.adjectives))))
+	if err != nil {
+		return "", err
+	}
+	noun, err := g.pick(big.NewInt(int64(len(g.nouns))))
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{g.adjectives[adjective], g.nouns[noun]}
+	if g.digits > 0 {
+		number, err := g.pick(g.numberRange)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%0*d", g.digits, number))
+	}
+
+	return strings.Join(parts, "-"), nil
+}
+
+func (g *WordGenerator) pick(n *big.Int) (int64, error) {
+	v, err := rand.Int(rand.Reader, n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read random number: %w", err)
+	}
+	return v.Int64(), nil
+}
+
+// Valid reports whether the code has the shape of a word short link. Words are not
+// looked up in the lists, so links stay valid when the lists change.
+func (g *WordGenerator) Valid(code string) bool {
+	parts := strings.Split(code, "-")
+	if g.digits > 0 {
+		if len(parts) != 3 || len(parts[2]) != g.digits || strings.Trim(parts[2], "0123456789") != "" {
+			return false
+		}
+	} else if len(parts) != 2 {
+		return false
+	}
+	return isLowerWord(parts[0]) && len(parts[0]) <= g.maxAdjLen &&
+		isLowerWord(parts[1]) && len(parts[1]) <= g.maxNounLen
+}
+
+// MaxLength returns the length of the longest short link the generator can produce.
+func (g *WordGenerator) MaxLength() int {
+	length := g.maxAdjLen + 1 + g.maxNounLen
+	if g.digits > 0 {
+		length += 1 + g.digits
+	}
+	return length
+}
+
+// Validator is implemented by generators whose short links do not follow the alphabet and length rules.
+type Validator interface {
+	Valid(string) bool
+}
@@ -0,0 +1,22 @@
+package ipmask
+
+import "net"
+
+const (
+	ipv4PrefixBits = 24
+	ipv6PrefixBits = 48
+)
+
+// Truncate zeroes the host part of the address so that it no longer identifies
+// a single client: IPv4 addresses keep their /24 network, IPv6 addresses their /48.
+// Unparsable input yields an empty string.
+func Truncate(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(ipv4PrefixBits, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(ipv6PrefixBits, 128)).String()
+}
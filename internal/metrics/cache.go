@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/cache"
+)
+
+type instrumentedCache struct {
+	cache.Cache
+	m *Metrics
+}
+
+// InstrumentCache counts the hits, misses and errors of cache lookups. Lookups made through
+// cache.Unwrap are not counted.
+func InstrumentCache(c cache.Cache, m *Metrics) cache.Cache {
+	return &instrumentedCache{Cache: c, m: m}
+}
+
+func (c *instrumentedCache) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.Cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		c.m.cacheLookups.WithLabelValues("hit").Inc()
+	case errors.Is(err, cache.ErrMiss):
+		c.m.cacheLookups.WithLabelValues("miss").Inc()
+	default:
+		c.m.cacheLookups.WithLabelValues("error").Inc()
+	}
+	return value, err
+}
+
+func (c *instrumentedCache) Set(ctx context.Context, key string, value string, maxTTL time.Duration) error {
+	return c.Cache.Set(ctx, key, value, maxTTL)
+}
+
+// Unwrap returns the cache without instrumentation.
+func (c *instrumentedCache) Unwrap() cache.Cache {
+	return c.Cache
+}
@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"net/http"
+
+	"url-shortener/internal/lib/generator"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/services"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "url_shortener"
+
+// Metrics holds the Prometheus collectors of the application in a dedicated registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests       *prometheus.CounterVec
+	httpDuration       *prometheus.HistogramVec
+	shortenOutcomes    *prometheus.CounterVec
+	cacheLookups       *prometheus.CounterVec
+	repositoryDuration *prometheus.HistogramVec
+	repositoryErrors   *prometheus.CounterVec
+}
+
+// New creates and registers the application collectors along with the Go runtime and process ones.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		shortenOutcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shorten_total",
+			Help:      "Number of shortened URLs by outcome.",
+		}, []string{"outcome"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Number of cache lookups by result: hit, miss or error.",
+		}, []string{"result"}),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_call_duration_seconds",
+			Help:      "Latency of repository calls by backend and operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"backend", "operation"}),
+		repositoryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_errors_total",
+			Help:      "Number of failed repository calls by backend and operation, not counting not found and taken short links.",
+		}, []string{"backend", "operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.shortenOutcomes,
+		m.cacheLookups,
+		m.repositoryDuration,
+		m.repositoryErrors,
+	)
+
+	return m
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDroppedClicks exposes the number of clicks the analytics queue had to drop.
+func (m *Metrics) RegisterDroppedClicks(analytics *services.AnalyticsService) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "analytics_dropped_clicks_total",
+		Help:      "Number of clicks dropped because the analytics queue was full.",
+	}, func() float64 {
+		return float64(analytics.Dropped())
+	}))
+}
+
+// RegisterCollisions exposes the number of generated short links the storage of the given
+// backend rejected as taken, each one causes a retry. Conflicting aliases are not counted.
+func (m *Metrics) RegisterCollisions(s interface{ Collisions() int64 }, backend string) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Name:        "short_link_collisions_total",
+		Help:        "Number of generated short links rejected by the repository because they are taken, each one causes a retry.",
+		ConstLabels: prometheus.Labels{"backend": backend},
+	}, func() float64 {
+		return float64(s.Collisions())
+	}))
+}
+
+// RegisterGeneratorStats exposes the collision statistics of the short link generator,
+// a growing collision rate means the short links are too short for the number of stored links.
+func (m *Metrics) RegisterGeneratorStats(g generator.CollisionReporter) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "generated_short_links_total",
+			Help:      "Number of short links generated.",
+		}, func() float64 {
+			return float64(g.Stats().Generated)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "generator_collisions_total",
+			Help:      "Number of generated short links that were already taken.",
+		}, func() float64 {
+			return float64(g.Stats().Collisions)
+		}),
+	)
+}
+
+// RegisterLinkLength exposes the current length of generated short links.
+func (m *Metrics) RegisterLinkLength(s interface{ LinkLength() int }) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "short_link_length",
+		Help:      "Current length of generated short links, it grows with the collision rate.",
+	}, func() float64 {
+		return float64(s.LinkLength())
+	}))
+}
+
+// RegisterKeyPool exposes the number of pre-generated keys in the shared pool and leased by this instance.
+func (m *Metrics) RegisterKeyPool(p *services.KeyPool) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "key_pool_depth",
+			Help:      "Number of pre-generated keys in the shared pool as of the last check.",
+		}, func() float64 {
+			return float64(p.Depth())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "key_pool_leased",
+			Help:      "Number of keys leased by this instance and not handed out yet.",
+		}, func() float64 {
+			return float64(p.Leased())
+		}),
+	)
+}
+
+// RegisterMemoryLinks exposes the number of links held by a bounded memory repository
+// and how many were evicted to stay within its limits.
+func (m *Metrics) RegisterMemoryLinks(r *memory.BoundedLinksRepo) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "memory_links",
+			Help:      "Number of links held by the bounded memory repository.",
+		}, func() float64 {
+			return float64(r.Len())
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "memory_evictions_total",
+			Help:      "Number of links evicted from the bounded memory repository.",
+		}, func() float64 {
+			return float64(r.Evictions())
+		}),
+	)
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+type instrumentedLinksRepo struct {
+	repo    repository.LinksRepo
+	backend string
+	m       *Metrics
+}
+
+// InstrumentLinksRepo records the latency and errors of every call to repo under the given backend name.
+// Short links rejected with ErrShortURLExists are not errors, LinkService counts the collisions it retries.
+func InstrumentLinksRepo(repo repository.LinksRepo, backend string, m *Metrics) repository.LinksRepo {
+	return &instrumentedLinksRepo{repo: repo, backend: backend, m: m}
+}
+
+func (r *instrumentedLinksRepo) observe(operation string, start time.Time, err error) {
+	r.m.repositoryDuration.WithLabelValues(r.backend, operation).Observe(time.Since(start).Seconds())
+
+	switch {
+	case err == nil, errors.Is(err, repository.ErrShortURLNotFound), errors.Is(err, repository.ErrShortURLExists):
+	default:
+		r.m.repositoryErrors.WithLabelValues(r.backend, operation).Inc()
+	}
+}
+
+func (r *instrumentedLinksRepo) Add(ctx context.Context, link domain.Link) (string, error) {
+	start := time.Now()
+	shortLink, err := r.repo.Add(ctx, link)
+	r.observe("add", start, err)
+	return shortLink, err
+}
+
+func (r *instrumentedLinksRepo) AddBatch(ctx context.Context, links []domain.Link) ([]repository.AddResult, error) {
+	start := time.Now()
+	results, err := r.repo.AddBatch(ctx, links)
+	r.observe("add_batch", start, err)
+	return results, err
+}
+
+func (r *instrumentedLinksRepo) GetByShortLink(ctx context.Context, shortLink string) (*domain.Link, error) {
+	start := time.Now()
+	link, err := r.repo.GetByShortLink(ctx, shortLink)
+	r.observe("get", start, err)
+	return link, err
+}
+
+func (r *instrumentedLinksRepo) Delete(ctx context.Context, shortLink string) error {
+	start := time.Now()
+	err := r.repo.Delete(ctx, shortLink)
+	r.observe("delete", start, err)
+	return err
+}
+
+func (r *instrumentedLinksRepo) SetDisabled(ctx context.Context, shortLink string, disabled bool) error {
+	start := time.Now()
+	err := r.repo.SetDisabled(ctx, shortLink, disabled)
+	r.observe("set_disabled", start, err)
+	return err
+}
+
+type instrumentedClicksRepo struct {
+	repo    repository.ClicksRepo
+	backend string
+	m       *Metrics
+}
+
+// InstrumentClicksRepo records the latency and errors of every call to repo under the given backend name.
+func InstrumentClicksRepo(repo repository.ClicksRepo, backend string, m *Metrics) repository.ClicksRepo {
+	return &instrumentedClicksRepo{repo: repo, backend: backend, m: m}
+}
+
+func (r *instrumentedClicksRepo) observe(operation string, start time.Time, err error) {
+	r.m.repositoryDuration.WithLabelValues(r.backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		r.m.repositoryErrors.WithLabelValues(r.backend, operation).Inc()
+	}
+}
+
+func (r *instrumentedClicksRepo) AddClicks(ctx context.Context, clicks []domain.Click) error {
+	start := time.Now()
+	err := r.repo.AddClicks(ctx, clicks)
+	r.observe("add_clicks", start, err)
+	return err
+}
+
+func (r *instrumentedClicksRepo) CountClicks(ctx context.Context, shortLink string, from, to time.Time, bucket time.Duration) ([]domain.ClickBucket, error) {
+	start := time.Now()
+	buckets, err := r.repo.CountClicks(ctx, shortLink, from, to, bucket)
+	r.observe("count_clicks", start, err)
+	return buckets, err
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+
+	"url-shortener/internal/services"
+)
+
+type instrumentedShortener struct {
+	services.Shortener
+	m *Metrics
+}
+
+// InstrumentShortener counts the outcome of every shortened URL, batch items are counted one by one.
+func InstrumentShortener(s services.Shortener, m *Metrics) services.Shortener {
+	return &instrumentedShortener{Shortener: s, m: m}
+}
+
+func (s *instrumentedShortener) Save(ctx context.Context, originalURL string, opts services.SaveOptions, retries int) (string, error) {
+	shortURL, err := s.Shortener.Save(ctx, originalURL, opts, retries)
+	s.m.shortenOutcomes.WithLabelValues(shortenOutcome(err)).Inc()
+	return shortURL, err
+}
+
+func (s *instrumentedShortener) SaveBatch(ctx context.Context, originalURLs []string, retries int) ([]services.BatchResult, error) {
+	results, err := s.Shortener.SaveBatch(ctx, originalURLs, retries)
+	if err != nil {
+		s.m.shortenOutcomes.WithLabelValues(shortenOutcome(err)).Add(float64(len(originalURLs)))
+		return results, err
+	}
+	for _, res := range results {
+		s.m.shortenOutcomes.WithLabelValues(shortenOutcome(res.Err)).Inc()
+	}
+	return results, nil
+}
+
+func shortenOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, services.ErrInvalidURL):
+		return "invalid_url"
+	case errors.Is(err, services.ErrMaxRetriesExceeded):
+		return "max_retries_exceeded"
+	case errors.Is(err, services.ErrInvalidStyle):
+		return "invalid_style"
+	case errors.Is(err, services.ErrInvalidExpiry):
+		return "invalid_expiry"
+	case errors.Is(err, services.ErrInvalidAlias), errors.Is(err, services.ErrReservedAlias):
+		return "invalid_alias"
+	case errors.Is(err, services.ErrAliasTaken), errors.Is(err, services.ErrURLExists), errors.Is(err, services.ErrExpiryConflict):
+		return "conflict"
+	default:
+		return "error"
+	}
+}
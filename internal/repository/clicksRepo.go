@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+type ClicksRepo interface {
+	AddClicks(context.Context, []domain.Click) error
+	// CountClicks returns the non-empty buckets of the given width in [from, to), ordered by start.
+	// Buckets are aligned to the Unix epoch.
+	CountClicks(ctx context.Context, shortLink string, from, to time.Time, bucket time.Duration) ([]domain.ClickBucket, error)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import "context"
+
+// KeysRepo stores pre-generated short links that have not been handed out yet.
+type KeysRepo interface {
+	// LeaseKeys removes up to n keys from the pool and returns them. Concurrent
+	// callers never receive the same key.
+	LeaseKeys(ctx context.Context, n int) ([]string, error)
+	// AddKeys puts keys into the pool, skipping the ones already pooled or used
+	// as a short link, and returns how many were added.
+	AddKeys(ctx context.Context, keys []string) (int, error)
+	// CountKeys returns the number of keys in the pool.
+	CountKeys(ctx context.Context) (int, error)
+}
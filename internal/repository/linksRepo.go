@@ -1,10 +1,25 @@
 package repository
 
 import (
+	"context"
+
 	"url-shortener/internal/domain"
 )
 
+// AddResult is the outcome of adding a single link of a batch.
+type AddResult struct {
+	ShortLink string
+	Err       error
+}
+
 type LinksRepo interface {
-	Add(domain.Link) (string, error)
-	GetByShortLink(string) (*domain.Link, error)
+	Add(context.Context, domain.Link) (string, error)
+	// AddBatch adds every link with the semantics of Add and returns the results in input order.
+	// A failure of one link does not prevent the others from being added.
+	AddBatch(context.Context, []domain.Link) ([]AddResult, error)
+	GetByShortLink(context.Context, string) (*domain.Link, error)
+	// Delete removes the link so that both its short link and original URL can be reused.
+	Delete(context.Context, string) error
+	// SetDisabled keeps the link stored but marks whether it may be resolved.
+	SetDisabled(context.Context, string, bool) error
 }
@@ -0,0 +1,329 @@
+package memory
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// entryOverhead approximates the memory taken by an entry besides its strings:
+// the entry itself, its slot in the eviction queue and in both indexes.
+const entryOverhead = 160
+
+var ErrInvalidBoundedConfig = errors.New("invalid bounded memory configuration")
+
+// EvictionPolicy tells which link is evicted when the repository is full.
+type EvictionPolicy string
+
+const (
+	// EvictLRU evicts the least recently used link.
+	EvictLRU EvictionPolicy = "lru"
+	// EvictLFU evicts the least frequently used link, the least recently used among equals.
+	EvictLFU EvictionPolicy = "lfu"
+)
+
+// BoundedConfig limits the number of links, their approximate size in bytes or both,
+// a zero limit is not enforced.
+type BoundedConfig struct {
+	Policy     EvictionPolicy
+	MaxEntries int
+	MaxBytes   int64
+	// TTL bounds how long a link of the next repository is served locally, so changes
+	// made by other instances are seen eventually. It is required with a next repository.
+	TTL time.Duration
+}
+
+type boundedEntry struct {
+	link     domain.Link
+	size     int64
+	uses     uint64
+	lastUse  uint64
+	storedAt time.Time
+	index    int // Position in the eviction queue
+}
+
+// BoundedLinksRepo keeps at most the configured number or size of links in memory,
+// evicting them by the configured policy. Both indexes of a link are evicted together.
+//
+// Without a next repository it is the storage itself and evicted links are lost. With
+// one it is a local tier in front of it: links are read through and cached, changes
+// are written to the next repository and drop the cached link.
+type BoundedLinksRepo struct {
+	cfg  BoundedConfig
+	next repository.LinksRepo
+
+	mu    sync.Mutex
+	links map[string]*boundedEntry // Short link to entry
+	urls  map[string]string        // Original URL to short link
+	queue evictionQueue
+	bytes int64
+	clock uint64
+	// generation counts the changes written through to the next repository, a link read
+	// through while it moved may be stale and is not cached.
+	generation uint64
+
+	evictions atomic.Uint64
+}
+
+// NewBoundedLinksRepo creates a bounded repository, in front of next unless it is nil.
+func NewBoundedLinksRepo(cfg BoundedConfig, next repository.LinksRepo) (*BoundedLinksRepo, error) {
+	if cfg.Policy != EvictLRU && cfg.Policy != EvictLFU {
+		return nil, ErrInvalidBoundedConfig
+	}
+	if cfg.MaxEntries < 0 || cfg.MaxBytes < 0 || cfg.TTL < 0 || (cfg.MaxEntries == 0 && cfg.MaxBytes == 0) {
+		return nil, ErrInvalidBoundedConfig
+	}
+	if next != nil && cfg.TTL == 0 {
+		return nil, ErrInvalidBoundedConfig
+	}
+
+	return &BoundedLinksRepo{
+		cfg:   cfg,
+		next:  next,
+		links: make(map[string]*boundedEntry),
+		urls:  make(map[string]string),
+		queue: evictionQueue{lfu: cfg.Policy == EvictLFU},
+	}, nil
+}
+
+// HealthCheck always succeeds, the health of a next repository is checked on its own.
+func (p *BoundedLinksRepo) HealthCheck(context.Context) error {
+	return nil
+}
+
+// Evictions returns the number of links evicted to stay within the limits.
+func (p *BoundedLinksRepo) Evictions() uint64 {
+	return p.evictions.Load()
+}
+
+// Len returns the number of links held in memory.
+func (p *BoundedLinksRepo) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.links)
+}
+
+func (p *BoundedLinksRepo) Add(ctx context.Context, link domain.Link) (string, error) {
+	if p.next != nil {
+		shortLink, err := p.next.Add(ctx, link)
+		if err == nil {
+			p.mu.Lock()
+			p.stored(link, shortLink)
+			p.mu.Unlock()
+		}
+		return shortLink, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if shortLink, ok := p.urls[link.OriginalURL]; ok {
+		e := p.links[shortLink]
+		p.touch(e)
+		if e.link.Expired(time.Now()) {
+			e.link.ExpiresAt = link.ExpiresAt
+		}
+		return shortLink, nil
+	}
+	if _, ok := p.links[link.ShortLink]; ok {
+		return "", repository.ErrShortURLExists
+	}
+	p.store(link)
+	return link.ShortLink, nil
+}
+
+func (p *BoundedLinksRepo) AddBatch(ctx context.Context, links []domain.Link) ([]repository.AddResult, error) {
+	if p.next == nil {
+		results := make([]repository.AddResult, len(links))
+		for i, link := range links {
+			results[i].ShortLink, results[i].Err = p.Add(ctx, link)
+		}
+		return results, nil
+	}
+
+	results, err := p.next.AddBatch(ctx, links)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, res := range results {
+		if res.Err == nil {
+			p.stored(links[i], res.ShortLink)
+		}
+	}
+	return results, nil
+}
+
+func (p *BoundedLinksRepo) GetByShortLink(ctx context.Context, shortLink string) (*domain.Link, error) {
+	p.mu.Lock()
+	if e, ok := p.links[shortLink]; ok {
+		if p.next == nil || time.Since(e.storedAt) < p.cfg.TTL {
+			p.touch(e)
+			link := e.link
+			p.mu.Unlock()
+			return &link, nil
+		}
+		p.remove(e)
+	}
+	generation := p.generation
+	p.mu.Unlock()
+
+	if p.next == nil {
+		return nil, repository.ErrShortURLNotFound
+	}
+	link, err := p.next.GetByShortLink(ctx, shortLink)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	if p.generation == generation {
+		p.store(*link)
+	}
+	p.mu.Unlock()
+	return link, nil
+}
+
+func (p *BoundedLinksRepo) Delete(ctx context.Context, shortLink string) error {
+	if p.next != nil {
+		err := p.next.Delete(ctx, shortLink)
+		p.forget(shortLink)
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.links[shortLink]
+	if !ok {
+		return repository.ErrShortURLNotFound
+	}
+	p.remove(e)
+	return nil
+}
+
+func (p *BoundedLinksRepo) SetDisabled(ctx context.Context, shortLink string, disabled bool) error {
+	if p.next != nil {
+		err := p.next.SetDisabled(ctx, shortLink, disabled)
+		p.forget(shortLink)
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.links[shortLink]
+	if !ok {
+		return repository.ErrShortURLNotFound
+	}
+	e.link.Disabled = disabled
+	return nil
+}
+
+// forget drops the cached link after it changed in the next repository.
+func (p *BoundedLinksRepo) forget(shortLink string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.generation++
+	if e, ok := p.links[shortLink]; ok {
+		p.remove(e)
+	}
+}
+
+// stored records that the next repository added link under shortLink. A new link is known
+// as a whole, while the existing one of a URL may have been revived with another deadline,
+// so it is dropped and read through when asked for.
+func (p *BoundedLinksRepo) stored(link domain.Link, shortLink string) {
+	if shortLink == link.ShortLink {
+		p.store(link)
+		return
+	}
+	p.generation++
+	if e, ok := p.links[shortLink]; ok {
+		p.remove(e)
+	}
+}
+
+// store adds or replaces the link and evicts others until the limits are met. A link
+// larger than MaxBytes on its own is still kept, as the only one.
+func (p *BoundedLinksRepo) store(link domain.Link) {
+	if e, ok := p.links[link.ShortLink]; ok {
+		p.remove(e)
+	}
+	if shortLink, ok := p.urls[link.OriginalURL]; ok {
+		p.remove(p.links[shortLink])
+	}
+
+	size := int64(len(link.ShortLink)+len(link.OriginalURL)) + entryOverhead
+	for len(p.links) > 0 && ((p.cfg.MaxEntries > 0 && len(p.links) >= p.cfg.MaxEntries) ||
+		(p.cfg.MaxBytes > 0 && p.bytes+size > p.cfg.MaxBytes)) {
+		p.remove(p.queue.entries[0])
+		p.evictions.Add(1)
+	}
+
+	p.clock++
+	e := &boundedEntry{link: link, size: size, uses: 1, lastUse: p.clock, storedAt: time.Now()}
+	p.links[link.ShortLink] = e
+	p.urls[link.OriginalURL] = link.ShortLink
+	p.bytes += size
+	heap.Push(&p.queue, e)
+}
+
+func (p *BoundedLinksRepo) remove(e *boundedEntry) {
+	heap.Remove(&p.queue, e.index)
+	delete(p.links, e.link.ShortLink)
+	if p.urls[e.link.OriginalURL] == e.link.ShortLink {
+		delete(p.urls, e.link.OriginalURL)
+	}
+	p.bytes -= e.size
+}
+
+func (p *BoundedLinksRepo) touch(e *boundedEntry) {
+	p.clock++
+	e.lastUse = p.clock
+	e.uses++
+	heap.Fix(&p.queue, e.index)
+}
+
+// evictionQueue is a heap of entries with the next one to evict on top.
+type evictionQueue struct {
+	entries []*boundedEntry
+	lfu     bool
+}
+
+func (q *evictionQueue) Len() int { return len(q.entries) }
+
+func (q *evictionQueue) Less(i, j int) bool {
+	a, b := q.entries[i], q.entries[j]
+	if q.lfu && a.uses != b.uses {
+		return a.uses < b.uses
+	}
+	return a.lastUse < b.lastUse
+}
+
+func (q *evictionQueue) Swap(i, j int) {
+	q.entries[i], q.entries[j] = q.entries[j], q.entries[i]
+	q.entries[i].index = i
+	q.entries[j].index = j
+}
+
+func (q *evictionQueue) Push(x any) {
+	e, _ := x.(*boundedEntry)
+	e.index = len(q.entries)
+	q.entries = append(q.entries, e)
+}
+
+func (q *evictionQueue) Pop() any {
+	last := len(q.entries) - 1
+	e := q.entries[last]
+	q.entries[last] = nil
+	q.entries = q.entries[:last]
+	return e
+}
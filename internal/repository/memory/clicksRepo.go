@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+type MemoryClicksRepo struct {
+	mu     sync.RWMutex
+	clicks map[string][]domain.Click // Short url to clicks mapping
+}
+
+func NewMemoryClicksRepo() *MemoryClicksRepo {
+	return &MemoryClicksRepo{clicks: make(map[string][]domain.Click)}
+}
+
+func (p *MemoryClicksRepo) AddClicks(_ context.Context, clicks []domain.Click) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, click := range clicks {
+		p.clicks[click.ShortLink] = append(p.clicks[click.ShortLink], click)
+	}
+	return nil
+}
+
+func (p *MemoryClicksRepo) CountClicks(_ context.Context, shortLink string, from, to time.Time, bucket time.Duration) ([]domain.ClickBucket, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	width := int64(bucket / time.Second)
+	counts := make(map[int64]int64)
+	for _, click := range p.clicks[shortLink] {
+		if click.At.Before(from) || !click.At.Before(to) {
+			continue
+		}
+		sec := click.At.Unix()
+		counts[sec-sec%width]++
+	}
+
+	buckets := make([]domain.ClickBucket, 0, len(counts))
+	for start, count := range counts {
+		buckets = append(buckets, domain.ClickBucket{Start: time.Unix(start, 0).UTC(), Count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+
+	return buckets, nil
+}
@@ -0,0 +1,401 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+const (
+	snapshotFile = "links.snapshot"
+	logFile      = "links.log"
+)
+
+var (
+	ErrInvalidDurableConfig = errors.New("invalid durable memory configuration")
+	ErrLogFailed            = errors.New("links log could not be restored after a failed write")
+)
+
+// FsyncPolicy tells when appended changes are flushed to the disk.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways flushes every change before it is acknowledged.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval flushes every FsyncInterval, a crash loses the changes of the last interval.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever leaves flushing to the operating system.
+	FsyncNever FsyncPolicy = "never"
+)
+
+type DurableConfig struct {
+	Fsync            FsyncPolicy
+	FsyncInterval    time.Duration
+	SnapshotInterval time.Duration
+}
+
+// DurableLinksRepo is a MemoryLinksRepo persisted to dir. Every change is appended to a log,
+// and every SnapshotInterval the links are written to a snapshot that replaces the log.
+// Reads are served from memory, changes are serialized to keep the log in their order.
+type DurableLinksRepo struct {
+	*MemoryLinksRepo
+	dir string
+	cfg DurableConfig
+
+	mu      sync.Mutex
+	wal     *os.File
+	size    int64 // Size of the log up to the last appended record
+	records int   // Records appended since the last snapshot
+	failed  error // Set when a failed append could not be cut from the log
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewDurableLinksRepo restores the links from the snapshot and log in dir, creating them
+// if needed. A damaged log tail, left by a crash in the middle of a write, is truncated.
+func NewDurableLinksRepo(dir string, cfg DurableConfig) (*DurableLinksRepo, error) {
+	switch cfg.Fsync {
+	case FsyncAlways, FsyncNever:
+	case FsyncInterval:
+		if cfg.FsyncInterval <= 0 {
+			return nil, ErrInvalidDurableConfig
+		}
+	default:
+		return nil, ErrInvalidDurableConfig
+	}
+	if cfg.SnapshotInterval <= 0 {
+		return nil, ErrInvalidDurableConfig
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	p := &DurableLinksRepo{
+		MemoryLinksRepo: NewMemoryLinksRepo(),
+		dir:             dir,
+		cfg:             cfg,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	if err := p.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := p.openLog(); err != nil {
+		return nil, err
+	}
+	go p.run()
+
+	return p, nil
+}
+
+func (p *DurableLinksRepo) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(p.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	// Snapshots are renamed into place once complete, so any damage is not a crash leftover.
+	if _, err := readRecords(f, p.apply); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return nil
+}
+
+func (p *DurableLinksRepo) openLog() error {
+	f, err := os.OpenFile(filepath.Join(p.dir, logFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+
+	valid, err := readRecords(f, func(rec logRecord) {
+		p.apply(rec)
+		p.records++
+	})
+	if errors.Is(err, errCorruptRecord) {
+		log.Default().Printf("Truncating corrupted tail of %s at offset %d", f.Name(), valid)
+		if err = f.Truncate(valid); err == nil {
+			err = f.Sync()
+		}
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to replay log: %w", err)
+	}
+
+	p.wal = f
+	p.size = valid
+	return nil
+}
+
+// apply changes the links in memory without logging.
+func (p *DurableLinksRepo) apply(rec logRecord) {
+	switch rec.Op {
+	case opPut:
+		p.put(rec.Link)
+	case opDelete:
+		p.forget(rec.Link.ShortLink)
+	}
+}
+
+// restore undoes a change of shortLink that could not be logged.
+func (p *DurableLinksRepo) restore(shortLink string, before domain.Link, existed bool) {
+	if existed {
+		p.apply(logRecord{Op: opPut, Link: before})
+	} else {
+		p.apply(logRecord{Op: opDelete, Link: domain.Link{ShortLink: shortLink}})
+	}
+}
+
+// append logs the record. A record that fails to be written or synced is cut from the log
+// again, as the change is refused: a partial one would hide the records appended after it
+// on replay, and a whole one would bring the change back.
+func (p *DurableLinksRepo) append(rec logRecord) error {
+	if p.failed != nil {
+		return p.failed
+	}
+
+	buf, err := encodeRecord(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode log record: %w", err)
+	}
+	if err := p.write(buf); err != nil {
+		p.rollback()
+		return err
+	}
+	p.size += int64(len(buf))
+	p.records++
+	return nil
+}
+
+func (p *DurableLinksRepo) write(buf []byte) error {
+	if _, err := p.wal.Write(buf); err != nil {
+		return fmt.Errorf("failed to append to log: %w", err)
+	}
+	if p.cfg.Fsync == FsyncAlways {
+		if err := p.wal.Sync(); err != nil {
+			return fmt.Errorf("failed to sync log: %w", err)
+		}
+	}
+	return nil
+}
+
+// rollback truncates the log to its size before a failed append. If that fails as well,
+// changes are refused until a snapshot replaces the log.
+func (p *DurableLinksRepo) rollback() {
+	err := p.wal.Truncate(p.size)
+	if err == nil {
+		err = p.wal.Sync()
+	}
+	if err != nil {
+		log.Default().Printf("Failed to truncate links log after a failed write: %v", err)
+		p.failed = fmt.Errorf("%w: %v", ErrLogFailed, err)
+	}
+}
+
+func (p *DurableLinksRepo) Add(ctx context.Context, link domain.Link) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.add(ctx, link)
+}
+
+func (p *DurableLinksRepo) AddBatch(ctx context.Context, links []domain.Link) ([]repository.AddResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	results := make([]repository.AddResult, len(links))
+	for i, link := range links {
+		results[i].ShortLink, results[i].Err = p.add(ctx, link)
+	}
+	return results, nil
+}
+
+// add logs the link when it is stored or an expired link is revived, a URL that is
+// already shortened changes nothing.
+func (p *DurableLinksRepo) add(ctx context.Context, link domain.Link) (string, error) {
+	before, existed := p.loadByURL(link.OriginalURL)
+
+	shortLink, err := p.MemoryLinksRepo.Add(ctx, link)
+	if err != nil {
+		return "", err
+	}
+	after, _ := p.load(shortLink)
+	if existed && before == after {
+		return shortLink, nil
+	}
+
+	if err := p.append(logRecord{Op: opPut, Link: after}); err != nil {
+		p.restore(shortLink, before, existed)
+		return "", err
+	}
+	return shortLink, nil
+}
+
+func (p *DurableLinksRepo) Delete(ctx context.Context, shortLink string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	before, _ := p.load(shortLink)
+	if err := p.MemoryLinksRepo.Delete(ctx, shortLink); err != nil {
+		return err
+	}
+
+	if err := p.append(logRecord{Op: opDelete, Link: domain.Link{ShortLink: shortLink}}); err != nil {
+		p.restore(shortLink, before, true)
+		return err
+	}
+	return nil
+}
+
+func (p *DurableLinksRepo) SetDisabled(ctx context.Context, shortLink string, disabled bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	before, _ := p.load(shortLink)
+	if err := p.MemoryLinksRepo.SetDisabled(ctx, shortLink, disabled); err != nil {
+		return err
+	}
+	after, _ := p.load(shortLink)
+	if before == after {
+		return nil
+	}
+
+	if err := p.append(logRecord{Op: opPut, Link: after}); err != nil {
+		p.restore(shortLink, before, true)
+		return err
+	}
+	return nil
+}
+
+// Snapshot writes all links to a new snapshot and empties the log. Changes wait for it
+// to finish. Nothing is written when the log is empty, unless it failed and is replaced.
+func (p *DurableLinksRepo) Snapshot() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.records == 0 && p.failed == nil {
+		return nil
+	}
+
+	path := filepath.Join(p.dir, snapshotFile)
+	if err := p.writeSnapshot(path + ".tmp"); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	if err := syncDir(p.dir); err != nil {
+		return fmt.Errorf("failed to sync data directory: %w", err)
+	}
+
+	// A crash before the truncation replays the log on top of the snapshot, which is
+	// harmless as the records only repeat changes the snapshot already holds.
+	if err := p.wal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate log: %w", err)
+	}
+	if err := p.wal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log: %w", err)
+	}
+	p.size = 0
+	p.records = 0
+	p.failed = nil
+	return nil
+}
+
+func (p *DurableLinksRepo) writeSnapshot(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	var writeErr error
+	p.each(func(link domain.Link) bool {
+		var buf []byte
+		if buf, writeErr = encodeRecord(logRecord{Op: opPut, Link: link}); writeErr == nil {
+			_, writeErr = w.Write(buf)
+		}
+		return writeErr == nil
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir makes a rename within dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close stops the background work, compacts the log into a snapshot and closes the log.
+func (p *DurableLinksRepo) Close() error {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+
+	snapshotErr := p.Snapshot()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return errors.Join(snapshotErr, p.wal.Sync(), p.wal.Close())
+}
+
+func (p *DurableLinksRepo) run() {
+	defer close(p.done)
+
+	snapshot := time.NewTicker(p.cfg.SnapshotInterval)
+	defer snapshot.Stop()
+
+	var fsync <-chan time.Time
+	if p.cfg.Fsync == FsyncInterval {
+		ticker := time.NewTicker(p.cfg.FsyncInterval)
+		defer ticker.Stop()
+		fsync = ticker.C
+	}
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-fsync:
+			p.mu.Lock()
+			err := p.wal.Sync()
+			p.mu.Unlock()
+			if err != nil {
+				log.Default().Printf("Failed to sync links log: %v", err)
+			}
+		case <-snapshot.C:
+			if err := p.Snapshot(); err != nil {
+				log.Default().Printf("Failed to snapshot links: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+type MemoryKeysRepo struct {
+	links *MemoryLinksRepo
+
+	mu     sync.Mutex
+	keys   []string
+	pooled map[string]bool
+}
+
+// NewMemoryKeysRepo creates a key pool that skips the short links stored in links.
+func NewMemoryKeysRepo(links *MemoryLinksRepo) *MemoryKeysRepo {
+	return &MemoryKeysRepo{links: links, pooled: make(map[string]bool)}
+}
+
+func (p *MemoryKeysRepo) LeaseKeys(_ context.Context, n int) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n = min(n, len(p.keys))
+	leased := make([]string, n)
+	copy(leased, p.keys[len(p.keys)-n:])
+	p.keys = p.keys[:len(p.keys)-n]
+	for _, key := range leased {
+		delete(p.pooled, key)
+	}
+	return leased, nil
+}
+
+func (p *MemoryKeysRepo) AddKeys(_ context.Context, keys []string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	added := 0
+	for _, key := range keys {
+		if p.pooled[key] {
+			continue
+		}
+		if _, used := p.links.load(key); used {
+			continue
+		}
+		p.pooled[key] = true
+		p.keys = append(p.keys, key)
+		added++
+	}
+	return added, nil
+}
+
+func (p *MemoryKeysRepo) CountKeys(context.Context) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.keys), nil
+}
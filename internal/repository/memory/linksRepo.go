@@ -1,36 +1,166 @@
 package memory
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"url-shortener/internal/domain"
 	"url-shortener/internal/repository"
 )
 
+// MemoryLinksRepo keeps the links in the process memory. Both indexes change together
+// under one lock, so every call sees either all or none of another one.
 type MemoryLinksRepo struct {
-	aliasMap sync.Map // Short to long url mapping
-	urlsMap  sync.Map // Long of short url mapping
+	mu    sync.RWMutex
+	links map[string]domain.Link // Short link to link
+	urls  map[string]string      // Original URL to short link
 }
 
 func NewMemoryLinksRepo() *MemoryLinksRepo {
-	return &MemoryLinksRepo{}
+	return &MemoryLinksRepo{
+		links: make(map[string]domain.Link),
+		urls:  make(map[string]string),
+	}
+}
+
+// HealthCheck always succeeds, the repository lives in the process memory.
+func (p *MemoryLinksRepo) HealthCheck(context.Context) error {
+	return nil
+}
+
+// Add stores the link unless its original URL is already shortened, an expired link
+// for the same URL is revived with the deadline of the new one. A short link taken by
+// another URL fails with repository.ErrShortURLExists.
+func (p *MemoryLinksRepo) Add(_ context.Context, link domain.Link) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.add(link)
+}
+
+func (p *MemoryLinksRepo) AddBatch(_ context.Context, links []domain.Link) ([]repository.AddResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	results := make([]repository.AddResult, len(links))
+	for i, link := range links {
+		results[i].ShortLink, results[i].Err = p.add(link)
+	}
+	return results, nil
+}
+
+func (p *MemoryLinksRepo) add(link domain.Link) (string, error) {
+	if shortLink, ok := p.urls[link.OriginalURL]; ok {
+		existing := p.links[shortLink]
+		if existing.Expired(time.Now()) {
+			existing.ExpiresAt = link.ExpiresAt
+			p.links[shortLink] = existing
+		}
+		return shortLink, nil
+	}
+	if _, ok := p.links[link.ShortLink]; ok {
+		return "", repository.ErrShortURLExists
+	}
+
+	p.links[link.ShortLink] = link
+	p.urls[link.OriginalURL] = link.ShortLink
+	return link.ShortLink, nil
 }
 
-func (p *MemoryLinksRepo) Add(linkDTO domain.Link) (string, error) {
-	loadedLink, isLoaded := p.urlsMap.LoadOrStore(linkDTO.OriginalURL, linkDTO.ShortLink)
-	if !isLoaded {
-		actualLink, _ := p.aliasMap.LoadOrStore(linkDTO.ShortLink, linkDTO.OriginalURL)
-		v, _ := actualLink.(string)
-		return v, nil
+func (p *MemoryLinksRepo) GetByShortLink(_ context.Context, shortLink string) (*domain.Link, error) {
+	link, ok := p.load(shortLink)
+	if !ok {
+		return nil, repository.ErrShortURLNotFound
 	}
-	v, _ := loadedLink.(string)
-	return v, nil
+	return &link, nil
 }
 
-func (p *MemoryLinksRepo) GetByShortLink(shortLink string) (*domain.Link, error) {
-	if originalURL, ok := p.aliasMap.Load(shortLink); ok {
-		v, _ := originalURL.(string)
-		return &domain.Link{ShortLink: shortLink, OriginalURL: v}, nil
+func (p *MemoryLinksRepo) Delete(_ context.Context, shortLink string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.links[shortLink]; !ok {
+		return repository.ErrShortURLNotFound
+	}
+	p.remove(shortLink)
+	return nil
+}
+
+func (p *MemoryLinksRepo) SetDisabled(_ context.Context, shortLink string, disabled bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	link, ok := p.links[shortLink]
+	if !ok {
+		return repository.ErrShortURLNotFound
+	}
+	link.Disabled = disabled
+	p.links[shortLink] = link
+	return nil
+}
+
+func (p *MemoryLinksRepo) load(shortLink string) (domain.Link, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	link, ok := p.links[shortLink]
+	return link, ok
+}
+
+// loadByURL returns the link the original URL is shortened to.
+func (p *MemoryLinksRepo) loadByURL(originalURL string) (domain.Link, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	shortLink, ok := p.urls[originalURL]
+	if !ok {
+		return domain.Link{}, false
+	}
+	return p.links[shortLink], true
+}
+
+// put stores the link as is, replacing the link of its short link and of its URL.
+func (p *MemoryLinksRepo) put(link domain.Link) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.links[link.ShortLink]; ok {
+		p.remove(link.ShortLink)
+	}
+	if shortLink, ok := p.urls[link.OriginalURL]; ok {
+		p.remove(shortLink)
+	}
+	p.links[link.ShortLink] = link
+	p.urls[link.OriginalURL] = link.ShortLink
+}
+
+// forget removes the link of shortLink if there is one.
+func (p *MemoryLinksRepo) forget(shortLink string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.links[shortLink]; ok {
+		p.remove(shortLink)
+	}
+}
+
+func (p *MemoryLinksRepo) remove(shortLink string) {
+	link := p.links[shortLink]
+	delete(p.links, shortLink)
+	if p.urls[link.OriginalURL] == shortLink {
+		delete(p.urls, link.OriginalURL)
+	}
+}
+
+// each calls fn for every link until it returns false, changes wait for it to finish.
+func (p *MemoryLinksRepo) each(fn func(domain.Link) bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, link := range p.links {
+		if !fn(link) {
+			return
+		}
 	}
-	return nil, repository.ErrShortURLNotFound
 }
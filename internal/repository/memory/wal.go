@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"url-shortener/internal/domain"
+)
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+
+	// Every record is framed by its payload size and CRC-32, both little endian.
+	recordHeaderSize = 8
+	maxRecordSize    = 1 << 20
+)
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// logRecord is a single change of the links. A put stores the whole link, so replaying
+// a record more than once leaves the same state.
+type logRecord struct {
+	Op   string      `json:"op"`
+	Link domain.Link `json:"link"`
+}
+
+func encodeRecord(rec logRecord) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	return append(buf, payload...), nil
+}
+
+// readRecords calls apply for every record of f in order and returns the size of the
+// valid prefix. A truncated or damaged record stops the reading with errCorruptRecord.
+func readRecords(f *os.File, apply func(logRecord)) (int64, error) {
+	r := bufio.NewReader(f)
+	header := make([]byte, recordHeaderSize)
+
+	var offset int64
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return offset, errCorruptRecord
+			}
+			return offset, err
+		}
+
+		size := binary.LittleEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			return offset, errCorruptRecord
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, errCorruptRecord
+			}
+			return offset, err
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+			return offset, errCorruptRecord
+		}
+
+		var rec logRecord
+		if err := json.Unmarshal(payload, &rec); err != nil || (rec.Op != opPut && rec.Op != opDelete) {
+			return offset, errCorruptRecord
+		}
+		apply(rec)
+		offset += recordHeaderSize + int64(size)
+	}
+}
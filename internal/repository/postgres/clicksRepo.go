@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+type PostgresClicksRepo struct {
+	db        *sql.DB
+	tableName string
+	timeout   time.Duration
+}
+
+// NewPostgresClicksRepo stores clicks in the clicks table, sharing the connection pool
+// and query timeout of the links repository.
+func NewPostgresClicksRepo(links *PostgresLinksRepo) *PostgresClicksRepo {
+	return &PostgresClicksRepo{db: links.db, tableName: links.tables.Clicks, timeout: links.timeout}
+}
+
+func (p *PostgresClicksRepo) AddClicks(ctx context.Context, clicks []domain.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	values := make([]string, 0, len(clicks))
+	args := make([]any, 0, 5*len(clicks))
+	for _, click := range clicks {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+		args = append(args, click.ShortLink, click.At, click.Referrer, click.UserAgent, click.IP)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (short_link, clicked_at, referrer, user_agent, ip)
+		VALUES %s;
+	`, p.tableName, strings.Join(values, ", "))
+
+	if _, err := p.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("error adding clicks to Postgres: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresClicksRepo) CountClicks(ctx context.Context, shortLink string, from, to time.Time, bucket time.Duration) ([]domain.ClickBucket, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		SELECT date_bin(make_interval(secs => $4), clicked_at, TIMESTAMPTZ 'epoch') AS bucket, COUNT(*)
+		FROM %s
+		WHERE short_link = $1 AND clicked_at >= $2 AND clicked_at < $3
+		GROUP BY bucket
+		ORDER BY bucket;
+	`, p.tableName)
+
+	rows, err := p.db.QueryContext(ctx, query, shortLink, from, to, bucket.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error counting clicks: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []domain.ClickBucket
+	for rows.Next() {
+		var b domain.ClickBucket
+		if err := rows.Scan(&b.Start, &b.Count); err != nil {
+			return nil, fmt.Errorf("error reading clicks: %w", err)
+		}
+		b.Start = b.Start.UTC()
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading clicks: %w", err)
+	}
+
+	return buckets, nil
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ConnConfig describes how to connect to Postgres. A DSN, either a URL or key=value
+// pairs, replaces the host, port, user, password and database name, while the TLS
+// settings, schema and statement timeout apply on top of either.
+type ConnConfig struct {
+	DSN      string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+
+	// SSLMode is one of disable, require, verify-ca and verify-full. Without a DSN
+	// it defaults to disable, otherwise to the mode of the DSN.
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	// Schema becomes the search path of every connection, empty keeps the server default.
+	Schema           string
+	StatementTimeout time.Duration
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// ConnString returns the connection string of the configuration in the key=value form.
+func (c ConnConfig) ConnString() (string, error) {
+	var params []string
+	add := func(key, value string) {
+		params = append(params, key+"="+quoteConnValue(value))
+	}
+
+	sslMode := c.SSLMode
+	if c.DSN != "" {
+		dsn := c.DSN
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			var err error
+			if dsn, err = pq.ParseURL(dsn); err != nil {
+				return "", fmt.Errorf("invalid Postgres URL: %w", err)
+			}
+		}
+		// Later parameters override the ones of the DSN.
+		params = append(params, dsn)
+	} else {
+		add("host", c.Host)
+		add("port", strconv.Itoa(c.Port))
+		add("user", c.User)
+		add("password", c.Password)
+		add("dbname", c.Name)
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+	}
+
+	for _, p := range []struct{ key, value string }{
+		{"sslmode", sslMode},
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+		{"search_path", c.Schema},
+	} {
+		if p.value != "" {
+			add(p.key, p.value)
+		}
+	}
+	if c.StatementTimeout > 0 {
+		add("statement_timeout", strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10))
+	}
+
+	return strings.Join(params, " "), nil
+}
+
+// quoteConnValue quotes a value of a key=value connection string.
+func quoteConnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// OpenDB connects to Postgres with a pool tuned by cfg and checks that the database
+// is reachable within timeout.
+func OpenDB(cfg ConnConfig, timeout time.Duration) (*sql.DB, error) {
+	dsn, err := cfg.ConnString()
+	if err != nil {
+		return nil, err
+	}
+	// Unlike sql.Open, the connector parses the connection string upfront.
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Postgres connection string: %w", err)
+	}
+
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	// sql.OpenDB does not connect, make sure the database is reachable before serving.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error pinging Postgres: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PostgresCounter hands out IDs from a Postgres sequence, so they are unique across instances.
+type PostgresCounter struct {
+	db           *sql.DB
+	sequenceName string
+	timeout      time.Duration
+}
+
+// NewPostgresCounter creates the sequence if needed, sharing the connection pool
+// and query timeout of the links repository. The sequence is not part of the versioned
+// migrations, its name is configured per deployment and only the sequence generator uses
+// it, so a renamed sequence would not be created by migrations applied before.
+func NewPostgresCounter(links *PostgresLinksRepo, sequenceName string) (*PostgresCounter, error) {
+	if _, err := links.db.Exec(fmt.Sprintf(`CREATE SEQUENCE IF NOT EXISTS %s`, sequenceName)); err != nil {
+		return nil, fmt.Errorf("failed to create sequence: %w", err)
+	}
+
+	return &PostgresCounter{db: links.db, sequenceName: sequenceName, timeout: links.timeout}, nil
+}
+
+func (p *PostgresCounter) Next(ctx context.Context) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var id int64
+	if err := p.db.QueryRowContext(ctx, `SELECT nextval($1)`, p.sequenceName).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get next value of sequence: %w", err)
+	}
+	return uint64(id), nil
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PostgresKeysRepo struct {
+	db         *sql.DB
+	tableName  string
+	linksTable string
+	timeout    time.Duration
+}
+
+// NewPostgresKeysRepo pools keys in the keys table, sharing the connection pool
+// and query timeout of the links repository.
+func NewPostgresKeysRepo(links *PostgresLinksRepo) *PostgresKeysRepo {
+	return &PostgresKeysRepo{db: links.db, tableName: links.tables.Keys, linksTable: links.tableName, timeout: links.timeout}
+}
+
+// LeaseKeys deletes and returns up to n keys. Rows locked by a concurrent lease are
+// skipped instead of waited for, so instances never block each other.
+func (p *PostgresKeysRepo) LeaseKeys(ctx context.Context, n int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE short_link IN (
+			SELECT short_link FROM %[1]s LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING short_link
+	`, p.tableName)
+
+	rows, err := p.db.QueryContext(ctx, query, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0, n)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lease keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (p *PostgresKeysRepo) AddKeys(ctx context.Context, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (short_link)
+		SELECT k FROM unnest($1::TEXT[]) AS k
+		WHERE NOT EXISTS (SELECT 1 FROM %[2]s l WHERE l.short_link = k)
+		ON CONFLICT DO NOTHING
+	`, p.tableName, p.linksTable)
+
+	res, err := p.db.ExecContext(ctx, query, pq.Array(keys))
+	if err != nil {
+		return 0, fmt.Errorf("failed to add keys: %w", err)
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add keys: %w", err)
+	}
+	return int(added), nil
+}
+
+func (p *PostgresKeysRepo) CountKeys(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var count int
+	if err := p.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT count(*) FROM %s`, p.tableName)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count keys: %w", err)
+	}
+	return count, nil
+}
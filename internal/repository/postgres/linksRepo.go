@@ -1,8 +1,11 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"url-shortener/internal/domain"
 	"url-shortener/internal/repository"
@@ -12,71 +15,157 @@ import (
 
 type PostgresLinksRepo struct {
 	db        *sql.DB
+	tables    Tables
 	tableName string
+	timeout   time.Duration
 }
 
-func NewPostgresLinksRepo(host string, port int, user, password, name, tableName string) (*PostgresLinksRepo, error) {
-	db, err := connectToDB(host, port, user, password, name)
+// NewPostgresLinksRepo connects to Postgres and, when migrate is set, applies the pending
+// schema migrations. Otherwise the schema must already be up to date. The schema of
+// tables becomes the search path of the connections. Every query is bounded by timeout.
+func NewPostgresLinksRepo(conn ConnConfig, tables Tables, migrate bool, timeout time.Duration) (*PostgresLinksRepo, error) {
+	conn.Schema = tables.Schema
+	db, err := OpenDB(conn, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
 	}
 
-	if err := migrateSchema(db, tableName); err != nil {
-		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	if err := prepareSchema(db, tables, migrate); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return &PostgresLinksRepo{db: db, tableName: tableName}, nil
+	return &PostgresLinksRepo{db: db, tables: tables, tableName: tables.Links, timeout: timeout}, nil
 }
 
-func connectToDB(host string, port int, user, password, name string) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, name,
-	)
-	db, err := sql.Open("postgres", dsn)
+// Close closes the connection pool, which is shared with the clicks repository.
+func (p *PostgresLinksRepo) Close() error {
+	return p.db.Close()
+}
+
+// HealthCheck reports whether Postgres accepts connections.
+func (p *PostgresLinksRepo) HealthCheck(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+// withTimeout bounds a single repository call by the configured query timeout.
+func (p *PostgresLinksRepo) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.timeout)
+}
+
+func prepareSchema(db *sql.DB, tables Tables, migrate bool) error {
+	migrator, err := NewMigrator(db, tables)
 	if err != nil {
-		return nil, fmt.Errorf("error connecting to Postgres: %w", err)
+		return err
 	}
-	return db, nil
-}
 
-func migrateSchema(db *sql.DB, tableName string) error {
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			id SERIAL PRIMARY KEY,
-			short_link CHARACTER(10) NOT NULL UNIQUE,
-			original_url TEXT NOT NULL UNIQUE
-		);
-		CREATE INDEX IF NOT EXISTS idx_short_link ON %s (short_link);
-		CREATE INDEX IF NOT EXISTS idx_original_url ON %s (original_url);
-	`, tableName, tableName, tableName)
-
-	if _, err := db.Exec(query); err != nil {
-		return fmt.Errorf("error executing migration: %w", err)
+	// Migrations wait for each other on a lock and may take long, so they are not bound by the query timeout.
+	ctx := context.Background()
+	if !migrate {
+		return migrator.Check(ctx)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
 	}
 	return nil
 }
 
-func (p *PostgresLinksRepo) Add(link domain.Link) (string, error) {
+// Add stores the link unless its original URL is already shortened. An expired
+// link for the same URL is revived with the deadline of the new one.
+func (p *PostgresLinksRepo) Add(ctx context.Context, link domain.Link) (string, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
 	query := fmt.Sprintf(`
-		INSERT INTO %s (short_link, original_url) 
-		VALUES ($1, $2)
-		ON CONFLICT (original_url) DO NOTHING
+		INSERT INTO %[1]s (short_link, original_url, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (original_url) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE %[1]s.expires_at IS NOT NULL AND %[1]s.expires_at <= NOW()
 		RETURNING short_link;
 	`, p.tableName)
 
 	var shortLink string
-	err := p.db.QueryRow(query, link.ShortLink, link.OriginalURL).Scan(&shortLink)
+	err := p.db.QueryRowContext(ctx, query, link.ShortLink, link.OriginalURL, link.ExpiresAt).Scan(&shortLink)
 	if err != nil {
-		return p.handleAddError(err, link.OriginalURL)
+		return p.handleAddError(ctx, err, link.OriginalURL)
 	}
 
 	return shortLink, nil
 }
 
-func (p *PostgresLinksRepo) handleAddError(err error, originalURL string) (string, error) {
+// AddBatch adds all links with a single multi-row insert. Links whose original URL is
+// already stored get the existing short link, links whose short link is taken by another
+// URL fail with repository.ErrShortURLExists.
+func (p *PostgresLinksRepo) AddBatch(ctx context.Context, links []domain.Link) ([]repository.AddResult, error) {
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	values := make([]string, 0, len(links))
+	args := make([]any, 0, 3*len(links))
+	for i, link := range links {
+		n := len(args)
+		values = append(values, fmt.Sprintf("(%d, $%d::VARCHAR, $%d::TEXT, $%d::TIMESTAMPTZ)", i, n+1, n+2, n+3))
+		args = append(args, link.ShortLink, link.OriginalURL, link.ExpiresAt)
+	}
+
+	// The final SELECT sees the table as it was before the statement, so rows
+	// inserted by this batch are taken from the RETURNING clause instead.
+	query := fmt.Sprintf(`
+		WITH input (ord, short_link, original_url, expires_at) AS (
+			VALUES %[2]s
+		), inserted AS (
+			INSERT INTO %[1]s (short_link, original_url, expires_at)
+			SELECT short_link, original_url, expires_at FROM input
+			ON CONFLICT DO NOTHING
+			RETURNING short_link, original_url
+		), revived AS (
+			UPDATE %[1]s SET expires_at = input.expires_at
+			FROM input
+			WHERE %[1]s.original_url = input.original_url
+				AND %[1]s.expires_at IS NOT NULL AND %[1]s.expires_at <= NOW()
+		)
+		SELECT input.ord, COALESCE(inserted.short_link, existing.short_link)
+		FROM input
+		LEFT JOIN inserted ON inserted.original_url = input.original_url
+		LEFT JOIN %[1]s existing ON existing.original_url = input.original_url
+		ORDER BY input.ord;
+	`, p.tableName, strings.Join(values, ", "))
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error adding links batch to Postgres: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]repository.AddResult, len(links))
+	for rows.Next() {
+		var (
+			ord       int
+			shortLink sql.NullString
+		)
+		if err := rows.Scan(&ord, &shortLink); err != nil {
+			return nil, fmt.Errorf("error reading links batch result: %w", err)
+		}
+		if shortLink.Valid {
+			results[ord].ShortLink = shortLink.String
+		} else {
+			results[ord].Err = repository.ErrShortURLExists
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading links batch result: %w", err)
+	}
+
+	return results, nil
+}
+
+func (p *PostgresLinksRepo) handleAddError(ctx context.Context, err error, originalURL string) (string, error) {
 	if err == sql.ErrNoRows {
-		return p.retrieveShortLink(originalURL)
+		return p.retrieveShortLink(ctx, originalURL)
 	}
 
 	if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
@@ -86,26 +175,33 @@ func (p *PostgresLinksRepo) handleAddError(err error, originalURL string) (strin
 	return "", fmt.Errorf("error adding link to Postgres: %w", err)
 }
 
-func (p *PostgresLinksRepo) retrieveShortLink(originalURL string) (string, error) {
+func (p *PostgresLinksRepo) retrieveShortLink(ctx context.Context, originalURL string) (string, error) {
 	query := fmt.Sprintf(`
 		SELECT short_link FROM %s WHERE original_url = $1;
 	`, p.tableName)
 
 	var shortLink string
-	if err := p.db.QueryRow(query, originalURL).Scan(&shortLink); err != nil {
+	if err := p.db.QueryRowContext(ctx, query, originalURL).Scan(&shortLink); err != nil {
 		return "", fmt.Errorf("error retrieving short link: %w", err)
 	}
 
 	return shortLink, nil
 }
 
-func (p *PostgresLinksRepo) GetByShortLink(shortLink string) (*domain.Link, error) {
+func (p *PostgresLinksRepo) GetByShortLink(ctx context.Context, shortLink string) (*domain.Link, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
 	query := fmt.Sprintf(`
-		SELECT original_url FROM %s WHERE short_link = $1;
+		SELECT original_url, expires_at, disabled FROM %s WHERE short_link = $1;
 	`, p.tableName)
 
-	var originalURL string
-	err := p.db.QueryRow(query, shortLink).Scan(&originalURL)
+	var (
+		originalURL string
+		expiresAt   sql.NullTime
+		disabled    bool
+	)
+	err := p.db.QueryRowContext(ctx, query, shortLink).Scan(&originalURL, &expiresAt, &disabled)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, repository.ErrShortURLNotFound
@@ -113,5 +209,51 @@ func (p *PostgresLinksRepo) GetByShortLink(shortLink string) (*domain.Link, erro
 		return nil, fmt.Errorf("error retrieving original URL: %w", err)
 	}
 
-	return &domain.Link{ShortLink: shortLink, OriginalURL: originalURL}, nil
+	link := &domain.Link{ShortLink: shortLink, OriginalURL: originalURL, Disabled: disabled}
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	return link, nil
+}
+
+func (p *PostgresLinksRepo) Delete(ctx context.Context, shortLink string) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		DELETE FROM %s WHERE short_link = $1;
+	`, p.tableName)
+
+	res, err := p.db.ExecContext(ctx, query, shortLink)
+	if err != nil {
+		return fmt.Errorf("error deleting link: %w", err)
+	}
+	return checkAffected(res)
+}
+
+func (p *PostgresLinksRepo) SetDisabled(ctx context.Context, shortLink string, disabled bool) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET disabled = $2 WHERE short_link = $1;
+	`, p.tableName)
+
+	res, err := p.db.ExecContext(ctx, query, shortLink, disabled)
+	if err != nil {
+		return fmt.Errorf("error updating link: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// checkAffected maps a statement that touched no rows to repository.ErrShortURLNotFound.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error reading affected rows: %w", err)
+	}
+	if n == 0 {
+		return repository.ErrShortURLNotFound
+	}
+	return nil
 }
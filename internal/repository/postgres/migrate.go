@@ -0,0 +1,352 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the key of the advisory lock held while migrating, so instances
+// starting together apply every migration once.
+const migrationLockID = 7_402_519_386
+
+const migrationsTable = "schema_migrations"
+
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+// Tables names the tables the migrations create and the schema holding them, which must
+// also be the search path of the connections. An empty schema keeps the server default.
+// MaxLength is the size of the short link column, the longest short link the service creates.
+type Tables struct {
+	Schema    string
+	Links     string
+	Clicks    string
+	Keys      string
+	MaxLength int
+}
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// Migrator applies the migrations embedded from migrations/. Every file is named
+// <version>_<name>.up.sql or <version>_<name>.down.sql and is a template of Tables.
+// Applied versions are recorded in the schema_migrations table.
+type Migrator struct {
+	db         *sql.DB
+	tables     Tables
+	migrations []migration
+}
+
+func NewMigrator(db *sql.DB, tables Tables) (*Migrator, error) {
+	if tables.MaxLength <= 0 {
+		return nil, fmt.Errorf("invalid short link length %d", tables.MaxLength)
+	}
+	migrations, err := loadMigrations(tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return &Migrator{db: db, tables: tables, migrations: migrations}, nil
+}
+
+func loadMigrations(tables Tables) ([]migration, error) {
+	paths, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, path := range paths {
+		file := strings.TrimPrefix(path, "migrations/")
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		versionStr, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !ok || !found || err != nil || version <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+
+		raw, err := migrationFiles.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(file).Option("missingkey=error").Parse(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", file, err)
+		}
+		var query strings.Builder
+		if err := tmpl.Execute(&query, tables); err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", file, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if m.name != name {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.name, name)
+		}
+		if direction == "up" {
+			m.up = query.String()
+		} else {
+			m.down = query.String()
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// Latest returns the version of the last embedded migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].version
+}
+
+// Version returns the highest applied version, 0 if none is applied, and the number
+// of embedded migrations that are not applied.
+func (m *Migrator) Version(ctx context.Context) (version, pending int, err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return 0, 0, err
+	}
+	for v := range applied {
+		version = max(version, v)
+	}
+	for _, mig := range m.migrations {
+		if !applied[mig.version] {
+			pending++
+		}
+	}
+	return version, pending, nil
+}
+
+// Up applies every migration that is not applied yet, in version order, and returns
+// how many were applied. Each migration runs in its own transaction. The short link
+// column is then resized if MaxLength changed since it was sized.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int]bool) error {
+		for _, mig := range m.migrations {
+			if applied[mig.version] {
+				continue
+			}
+			err := runInTx(ctx, conn, mig.up,
+				fmt.Sprintf(`INSERT INTO %s (version, name) VALUES ($1, $2)`, migrationsTable), mig.version, mig.name)
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", mig.version, mig.name, err)
+			}
+			count++
+		}
+		return m.sizeShortLinks(ctx, conn)
+	})
+	return count, err
+}
+
+// Down reverts the last steps applied migrations and returns how many were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int]bool) error {
+		for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+			mig := m.migrations[i]
+			if !applied[mig.version] {
+				continue
+			}
+			if mig.down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted, it has no down file", mig.version, mig.name)
+			}
+			err := runInTx(ctx, conn, mig.down,
+				fmt.Sprintf(`DELETE FROM %s WHERE version = $1`, migrationsTable), mig.version)
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", mig.version, mig.name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Check fails with ErrSchemaOutdated when some migration is not applied or the short link
+// column is shorter than MaxLength.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, pending, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: version %d with %d pending migrations, latest is %d", ErrSchemaOutdated, version, pending, m.Latest())
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+	length, err := m.shortLinkLength(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if length < m.tables.MaxLength {
+		return fmt.Errorf("%w: short links hold up to %d characters, %d are configured", ErrSchemaOutdated, length, m.tables.MaxLength)
+	}
+	return nil
+}
+
+// sizeShortLinks resizes the short link column to MaxLength. Shrinking it fails while
+// longer short links are stored.
+func (m *Migrator) sizeShortLinks(ctx context.Context, conn *sql.Conn) error {
+	length, err := m.shortLinkLength(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if length == m.tables.MaxLength {
+		return nil
+	}
+	query := fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN short_link TYPE VARCHAR(%d)`, m.tables.Links, m.tables.MaxLength)
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to resize short links from %d to %d characters: %w", length, m.tables.MaxLength, err)
+	}
+	return nil
+}
+
+// shortLinkLength returns the size of the short link column, 0 when it is unbounded.
+func (m *Migrator) shortLinkLength(ctx context.Context, conn *sql.Conn) (int, error) {
+	var length sql.NullInt64
+	err := conn.QueryRowContext(ctx, `
+		SELECT character_maximum_length FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema())
+			AND table_name = $2 AND column_name = 'short_link'
+	`, m.tables.Schema, m.tables.Links).Scan(&length)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up short link column of %s: %w", m.tables.Links, err)
+	}
+	return int(length.Int64), nil
+}
+
+// locked runs fn on a single connection holding the migration lock. Session advisory
+// locks belong to a connection, so the lock and the migrations must share one. Waiting
+// for the lock and rewriting a large table take longer than queries, so the statement
+// timeout of the connection is lifted until it goes back to the pool.
+func (m *Migrator) locked(ctx context.Context, fn func(*sql.Conn, map[int]bool) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SET statement_timeout = 0`); err != nil {
+		return fmt.Errorf("failed to lift statement timeout: %w", err)
+	}
+	defer func() {
+		if _, resetErr := conn.ExecContext(context.Background(), `RESET statement_timeout`); resetErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to reset statement timeout: %w", resetErr))
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		// The lock is released with the connection anyway if unlocking fails.
+		if _, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release migration lock: %w", unlockErr))
+		}
+	}()
+
+	if m.tables.Schema != "" {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, m.tables.Schema)); err != nil {
+			return fmt.Errorf("failed to create schema %s: %w", m.tables.Schema, err)
+		}
+	}
+
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+	`, migrationsTable)
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s: %w", migrationsTable, err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+func runInTx(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	// A database never migrated has no table yet, which reads as no applied versions.
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, migrationsTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", migrationsTable, err)
+	}
+	applied := make(map[int]bool)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT version FROM %s`, migrationsTable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
@@ -0,0 +1,7 @@
+-- Tables created before versioned migrations are adopted as they are. The unique
+-- constraints index both columns.
+CREATE TABLE IF NOT EXISTS {{.Links}} (
+	id SERIAL PRIMARY KEY,
+	short_link TEXT NOT NULL UNIQUE,
+	original_url TEXT NOT NULL UNIQUE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE {{.Links}} DROP COLUMN IF EXISTS disabled;
+ALTER TABLE {{.Links}} DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,2 @@
+ALTER TABLE {{.Links}} ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE {{.Links}} ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
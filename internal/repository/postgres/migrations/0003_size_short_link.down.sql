@@ -0,0 +1 @@
+ALTER TABLE {{.Links}} ALTER COLUMN short_link TYPE TEXT;
@@ -0,0 +1,3 @@
+-- Sized to the longest short link the configuration allows, Migrator.Up resizes it when
+-- the configuration changes.
+ALTER TABLE {{.Links}} ALTER COLUMN short_link TYPE VARCHAR({{.MaxLength}});
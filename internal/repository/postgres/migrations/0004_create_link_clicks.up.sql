@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS {{.Clicks}} (
+	id BIGSERIAL PRIMARY KEY,
+	short_link TEXT NOT NULL,
+	clicked_at TIMESTAMPTZ NOT NULL,
+	referrer TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_{{.Clicks}}_short_link_clicked_at ON {{.Clicks}} (short_link, clicked_at);
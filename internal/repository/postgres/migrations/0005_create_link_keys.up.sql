@@ -0,0 +1 @@
+CREATE TABLE IF NOT EXISTS {{.Keys}} (short_link TEXT PRIMARY KEY);
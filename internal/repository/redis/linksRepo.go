@@ -0,0 +1,280 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// addScript stores a link and its URL index entry unless the URL is already shortened,
+// in which case the existing short link is returned and revived if it expired. A taken
+// short link is answered with nil. The link key of an existing URL is built from the prefix,
+// so the script needs a single Redis instance rather than a cluster.
+//
+// KEYS: link hash, URL index. ARGV: short link, original URL, deadline in Unix
+// milliseconds or empty, now in Unix milliseconds, link key prefix.
+var addScript = goredis.NewScript(`
+local existing = redis.call('HGET', KEYS[2], ARGV[2])
+if existing then
+	local key = ARGV[5] .. existing
+	local expiresAt = redis.call('HGET', key, 'expires_at')
+	if expiresAt and tonumber(expiresAt) <= tonumber(ARGV[4]) then
+		if ARGV[3] == '' then
+			redis.call('HDEL', key, 'expires_at')
+		else
+			redis.call('HSET', key, 'expires_at', ARGV[3])
+		end
+	end
+	return existing
+end
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return false
+end
+redis.call('HSET', KEYS[1], 'url', ARGV[2], 'disabled', '0')
+if ARGV[3] ~= '' then
+	redis.call('HSET', KEYS[1], 'expires_at', ARGV[3])
+end
+redis.call('HSET', KEYS[2], ARGV[2], ARGV[1])
+return ARGV[1]
+`)
+
+// deleteScript removes a link and its URL index entry, answering 0 if there is no link.
+//
+// KEYS: link hash, URL index. ARGV: short link.
+var deleteScript = goredis.NewScript(`
+local url = redis.call('HGET', KEYS[1], 'url')
+if not url then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+if redis.call('HGET', KEYS[2], url) == ARGV[1] then
+	redis.call('HDEL', KEYS[2], url)
+end
+return 1
+`)
+
+// setDisabledScript updates an existing link, answering 0 if there is no link.
+//
+// KEYS: link hash. ARGV: "1" to disable, "0" to enable.
+var setDisabledScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'disabled', ARGV[1])
+return 1
+`)
+
+// RedisLinksRepo stores every link in a hash at <prefix>link:<short link> and indexes
+// them by original URL in the hash <prefix>urls. Both are changed together by Lua scripts.
+type RedisLinksRepo struct {
+	client  *goredis.Client
+	prefix  string
+	timeout time.Duration
+}
+
+// NewRedisLinksRepo connects to Redis and keeps the links under prefix, which should
+// contain a character outside of the short link alphabet to stay apart from the cache
+// keys. Every call is bounded by timeout. It fails if Redis does not answer a PING.
+func NewRedisLinksRepo(host string, port int, password string, db int, prefix string, timeout time.Duration) (*RedisLinksRepo, error) {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:     fmt.Sprintf("%s:%d", host, port),
+		Password: password,
+		DB:       db,
+	})
+
+	r := &RedisLinksRepo{client: client, prefix: prefix, timeout: timeout}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := r.HealthCheck(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error pinging Redis: %w", err)
+	}
+
+	// Batches run the scripts by their hash in a pipeline, which needs them loaded.
+	for _, script := range []*goredis.Script{addScript, deleteScript, setDisabledScript} {
+		if err := script.Load(ctx, client).Err(); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("error loading Redis scripts: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// HealthCheck reports whether Redis answers a PING.
+func (r *RedisLinksRepo) HealthCheck(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Close closes the connections to Redis.
+func (r *RedisLinksRepo) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisLinksRepo) linkKey(shortLink string) string {
+	return r.prefix + "link:" + shortLink
+}
+
+func (r *RedisLinksRepo) urlsKey() string {
+	return r.prefix + "urls"
+}
+
+func (r *RedisLinksRepo) addArgs(link domain.Link, now time.Time) ([]string, []any) {
+	expiresAt := ""
+	if link.ExpiresAt != nil {
+		expiresAt = strconv.FormatInt(link.ExpiresAt.UnixMilli(), 10)
+	}
+	keys := []string{r.linkKey(link.ShortLink), r.urlsKey()}
+	args := []any{link.ShortLink, link.OriginalURL, expiresAt, now.UnixMilli(), r.prefix + "link:"}
+	return keys, args
+}
+
+// Add stores the link unless its original URL is already shortened. An expired
+// link for the same URL is revived with the deadline of the new one.
+func (r *RedisLinksRepo) Add(ctx context.Context, link domain.Link) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	keys, args := r.addArgs(link, time.Now())
+	shortLink, err := addScript.Run(ctx, r.client, keys, args...).Text()
+	return handleAddResult(shortLink, err)
+}
+
+// AddBatch runs the additions in a single pipeline, each of them is atomic on its own.
+func (r *RedisLinksRepo) AddBatch(ctx context.Context, links []domain.Link) ([]repository.AddResult, error) {
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	now := time.Now()
+	cmds := make([]*goredis.Cmd, len(links))
+	pending := make([]int, len(links))
+	for i := range links {
+		pending[i] = i
+	}
+	if err := r.pipelineAdds(ctx, links, now, pending, cmds); err != nil {
+		return nil, err
+	}
+
+	// The scripts are gone if Redis restarted or flushed them since they were loaded,
+	// they are loaded again and the additions that missed them run once more.
+	var missing []int
+	for i, cmd := range cmds {
+		if err := cmd.Err(); err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT ") {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		if err := addScript.Load(ctx, r.client).Err(); err != nil {
+			return nil, fmt.Errorf("error loading Redis scripts: %w", err)
+		}
+		if err := r.pipelineAdds(ctx, links, now, missing, cmds); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]repository.AddResult, len(links))
+	for i, cmd := range cmds {
+		shortLink, err := cmd.Text()
+		results[i].ShortLink, err = handleAddResult(shortLink, err)
+		if err != nil && !errors.Is(err, repository.ErrShortURLExists) {
+			return nil, err
+		}
+		results[i].Err = err
+	}
+	return results, nil
+}
+
+// pipelineAdds runs the additions of the links at indexes in a single pipeline and sets
+// their commands in cmds.
+func (r *RedisLinksRepo) pipelineAdds(ctx context.Context, links []domain.Link, now time.Time, indexes []int, cmds []*goredis.Cmd) error {
+	pipe := r.client.Pipeline()
+	for _, i := range indexes {
+		keys, args := r.addArgs(links[i], now)
+		cmds[i] = addScript.EvalSha(ctx, pipe, keys, args...)
+	}
+	// Exec reports the first failed command, the result of each one is read by the caller.
+	if _, err := pipe.Exec(ctx); err != nil && ctx.Err() != nil {
+		return fmt.Errorf("error adding links batch to Redis: %w", err)
+	}
+	return nil
+}
+
+func handleAddResult(shortLink string, err error) (string, error) {
+	if errors.Is(err, goredis.Nil) {
+		return "", repository.ErrShortURLExists
+	}
+	if err != nil {
+		return "", fmt.Errorf("error adding link to Redis: %w", err)
+	}
+	return shortLink, nil
+}
+
+func (r *RedisLinksRepo) GetByShortLink(ctx context.Context, shortLink string) (*domain.Link, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	fields, err := r.client.HGetAll(ctx, r.linkKey(shortLink)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving original URL: %w", err)
+	}
+	originalURL, ok := fields["url"]
+	if !ok {
+		return nil, repository.ErrShortURLNotFound
+	}
+
+	link := &domain.Link{ShortLink: shortLink, OriginalURL: originalURL, Disabled: fields["disabled"] == "1"}
+	if v, ok := fields["expires_at"]; ok {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing link deadline: %w", err)
+		}
+		expiresAt := time.UnixMilli(ms).UTC()
+		link.ExpiresAt = &expiresAt
+	}
+	return link, nil
+}
+
+func (r *RedisLinksRepo) Delete(ctx context.Context, shortLink string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	n, err := deleteScript.Run(ctx, r.client, []string{r.linkKey(shortLink), r.urlsKey()}, shortLink).Int()
+	if err != nil {
+		return fmt.Errorf("error deleting link: %w", err)
+	}
+	if n == 0 {
+		return repository.ErrShortURLNotFound
+	}
+	return nil
+}
+
+func (r *RedisLinksRepo) SetDisabled(ctx context.Context, shortLink string, disabled bool) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	flag := "0"
+	if disabled {
+		flag = "1"
+	}
+	n, err := setDisabledScript.Run(ctx, r.client, []string{r.linkKey(shortLink)}, flag).Int()
+	if err != nil {
+		return fmt.Errorf("error updating link: %w", err)
+	}
+	if n == 0 {
+		return repository.ErrShortURLNotFound
+	}
+	return nil
+}
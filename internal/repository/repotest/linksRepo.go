@@ -0,0 +1,342 @@
+// Package repotest holds the conformance suite every repository.LinksRepo must pass,
+// so that the storages can be swapped without changing the behaviour of the service.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewLinksRepo returns an empty repository for a single test, releasing it is left
+// to the factory, e.g. with t.Cleanup.
+type NewLinksRepo func(t *testing.T) repository.LinksRepo
+
+// TestLinksRepo runs the conformance suite against repositories created by newRepo.
+// Run it with -race, part of the suite adds links concurrently.
+func TestLinksRepo(t *testing.T, newRepo NewLinksRepo) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, repository.LinksRepo)
+	}{
+		{"AddAndGet", testAddAndGet},
+		{"DeduplicatesURL", testDeduplicatesURL},
+		{"Collision", testCollision},
+		{"NotFound", testNotFound},
+		{"Expiry", testExpiry},
+		{"Delete", testDelete},
+		{"SetDisabled", testSetDisabled},
+		{"AddBatch", testAddBatch},
+		{"ConcurrentSameURL", testConcurrentSameURL},
+		{"ConcurrentSameShortLink", testConcurrentSameShortLink},
+		{"ConcurrentStress", testConcurrentStress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func link(shortLink, originalURL string) domain.Link {
+	return domain.Link{ShortLink: shortLink, OriginalURL: originalURL}
+}
+
+// deadline returns a time that every storage keeps exactly.
+func deadline(d time.Duration) *time.Time {
+	t := time.Now().Add(d).Truncate(time.Millisecond).UTC()
+	return &t
+}
+
+func testAddAndGet(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	shortLink, err := repo.Add(ctx, link("aaaaa", "https://example.com/a"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+
+	got, err := repo.GetByShortLink(ctx, "aaaaa")
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaa", got.ShortLink)
+	assert.Equal(t, "https://example.com/a", got.OriginalURL)
+	assert.Nil(t, got.ExpiresAt)
+	assert.False(t, got.Disabled)
+}
+
+func testDeduplicatesURL(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	_, err := repo.Add(ctx, link("aaaaa", "https://example.com/a"))
+	require.NoError(t, err)
+
+	shortLink, err := repo.Add(ctx, link("bbbbb", "https://example.com/a"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink, "a shortened URL keeps its short link")
+
+	_, err = repo.GetByShortLink(ctx, "bbbbb")
+	assert.ErrorIs(t, err, repository.ErrShortURLNotFound, "the short link of a duplicate is not stored")
+}
+
+func testCollision(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	_, err := repo.Add(ctx, link("aaaaa", "https://example.com/a"))
+	require.NoError(t, err)
+
+	_, err = repo.Add(ctx, link("aaaaa", "https://example.com/b"))
+	assert.ErrorIs(t, err, repository.ErrShortURLExists)
+
+	got, err := repo.GetByShortLink(ctx, "aaaaa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", got.OriginalURL, "a collision does not change the stored link")
+
+	// The URL of the rejected link is not stored, a retry with another short link succeeds
+	shortLink, err := repo.Add(ctx, link("bbbbb", "https://example.com/b"))
+	require.NoError(t, err)
+	assert.Equal(t, "bbbbb", shortLink)
+}
+
+func testNotFound(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	_, err := repo.GetByShortLink(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, "missing"), repository.ErrShortURLNotFound)
+	assert.ErrorIs(t, repo.SetDisabled(ctx, "missing", true), repository.ErrShortURLNotFound)
+}
+
+func testExpiry(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	expiresAt := deadline(time.Hour)
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/a", ExpiresAt: expiresAt})
+	require.NoError(t, err)
+
+	got, err := repo.GetByShortLink(ctx, "aaaaa")
+	require.NoError(t, err)
+	require.NotNil(t, got.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*got.ExpiresAt), "expected %v, got %v", expiresAt, got.ExpiresAt)
+
+	// A live link keeps its deadline when the URL is shortened again
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com/a", ExpiresAt: deadline(2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+	got, err = repo.GetByShortLink(ctx, "aaaaa")
+	require.NoError(t, err)
+	assert.True(t, expiresAt.Equal(*got.ExpiresAt))
+
+	// An expired link is revived with the new deadline
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "ccccc", OriginalURL: "https://example.com/c", ExpiresAt: deadline(-time.Hour)})
+	require.NoError(t, err)
+	revivedAt := deadline(time.Hour)
+	shortLink, err = repo.Add(ctx, domain.Link{ShortLink: "ddddd", OriginalURL: "https://example.com/c", ExpiresAt: revivedAt})
+	require.NoError(t, err)
+	assert.Equal(t, "ccccc", shortLink)
+	got, err = repo.GetByShortLink(ctx, "ccccc")
+	require.NoError(t, err)
+	require.NotNil(t, got.ExpiresAt)
+	assert.True(t, revivedAt.Equal(*got.ExpiresAt), "expected %v, got %v", revivedAt, got.ExpiresAt)
+}
+
+func testDelete(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	_, err := repo.Add(ctx, link("aaaaa", "https://example.com/a"))
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, "aaaaa"))
+
+	_, err = repo.GetByShortLink(ctx, "aaaaa")
+	assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, "aaaaa"), repository.ErrShortURLNotFound)
+
+	// Both the short link and the URL can be reused
+	shortLink, err := repo.Add(ctx, link("bbbbb", "https://example.com/a"))
+	require.NoError(t, err)
+	assert.Equal(t, "bbbbb", shortLink)
+	shortLink, err = repo.Add(ctx, link("aaaaa", "https://example.com/b"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+}
+
+func testSetDisabled(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	_, err := repo.Add(ctx, link("aaaaa", "https://example.com/a"))
+	require.NoError(t, err)
+
+	for _, disabled := range []bool{true, true, false} {
+		require.NoError(t, repo.SetDisabled(ctx, "aaaaa", disabled))
+		got, err := repo.GetByShortLink(ctx, "aaaaa")
+		require.NoError(t, err)
+		assert.Equal(t, disabled, got.Disabled)
+		assert.Equal(t, "https://example.com/a", got.OriginalURL)
+	}
+}
+
+func testAddBatch(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	_, err := repo.Add(ctx, link("aaaaa", "https://example.com/a"))
+	require.NoError(t, err)
+
+	results, err := repo.AddBatch(ctx, []domain.Link{
+		link("bbbbb", "https://example.com/b"),
+		link("ccccc", "https://example.com/a"),
+		link("aaaaa", "https://example.com/d"),
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, repository.AddResult{ShortLink: "bbbbb"}, results[0])
+	assert.Equal(t, repository.AddResult{ShortLink: "aaaaa"}, results[1])
+	assert.ErrorIs(t, results[2].Err, repository.ErrShortURLExists)
+
+	results, err = repo.AddBatch(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+const workers = 8
+
+// testConcurrentSameURL shortens one URL from many goroutines, all of them must get
+// the same short link.
+func testConcurrentSameURL(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	shortLinks := make([]string, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shortLinks[i], errs[i] = repo.Add(ctx, link(fmt.Sprintf("same%d", i), "https://example.com/same"))
+		}()
+	}
+	wg.Wait()
+
+	for i := range workers {
+		require.NoError(t, errs[i])
+		assert.Equal(t, shortLinks[0], shortLinks[i])
+	}
+	for i := range workers {
+		got, err := repo.GetByShortLink(ctx, fmt.Sprintf("same%d", i))
+		if fmt.Sprintf("same%d", i) == shortLinks[0] {
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/same", got.OriginalURL)
+		} else {
+			assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+		}
+	}
+}
+
+// testConcurrentSameShortLink stores different URLs under one short link from many
+// goroutines, exactly one of them must win.
+func testConcurrentSameShortLink(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = repo.Add(ctx, link("taken", fmt.Sprintf("https://example.com/%d", i)))
+		}()
+	}
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		if err == nil {
+			assert.Equal(t, -1, winner, "more than one URL got the short link")
+			winner = i
+			continue
+		}
+		assert.ErrorIs(t, err, repository.ErrShortURLExists)
+	}
+	require.NotEqual(t, -1, winner, "no URL got the short link")
+
+	got, err := repo.GetByShortLink(ctx, "taken")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("https://example.com/%d", winner), got.OriginalURL)
+
+	// The losing URLs are not left behind in the URL index
+	for i := range workers {
+		if i == winner {
+			continue
+		}
+		shortLink, err := repo.Add(ctx, link(fmt.Sprintf("retry%d", i), fmt.Sprintf("https://example.com/%d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("retry%d", i), shortLink)
+	}
+}
+
+// testConcurrentStress adds, reads and deletes overlapping links from many goroutines
+// and then checks that both directions of the mapping agree.
+func testConcurrentStress(t *testing.T, repo repository.LinksRepo) {
+	ctx := context.Background()
+	const (
+		rounds     = 50
+		shortLinks = 10
+		urls       = 10
+	)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				shortLink := fmt.Sprintf("s%d", (w+i)%shortLinks)
+				originalURL := fmt.Sprintf("https://example.com/%d", (w*7+i)%urls)
+				switch i % 5 {
+				case 0, 1, 2:
+					_, err := repo.Add(ctx, link(shortLink, originalURL))
+					if err != nil {
+						assert.ErrorIs(t, err, repository.ErrShortURLExists)
+					}
+				case 3:
+					if _, err := repo.GetByShortLink(ctx, shortLink); err != nil {
+						assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+					}
+				case 4:
+					if err := repo.Delete(ctx, shortLink); err != nil {
+						assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every stored link is found by its URL
+	for i := range shortLinks {
+		shortLink := fmt.Sprintf("s%d", i)
+		got, err := repo.GetByShortLink(ctx, shortLink)
+		if err != nil {
+			assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+			continue
+		}
+		existing, err := repo.Add(ctx, link("fresh"+shortLink, got.OriginalURL))
+		require.NoError(t, err)
+		assert.Equal(t, shortLink, existing, "%s is stored for %s but the URL maps elsewhere", shortLink, got.OriginalURL)
+	}
+
+	// Every URL maps to a link stored for it
+	for i := range urls {
+		originalURL := fmt.Sprintf("https://example.com/%d", i)
+		shortLink, err := repo.Add(ctx, link(fmt.Sprintf("url%d", i), originalURL))
+		require.NoError(t, err)
+		got, err := repo.GetByShortLink(ctx, shortLink)
+		require.NoError(t, err, "%s maps to %s which is not stored", originalURL, shortLink)
+		assert.Equal(t, originalURL, got.OriginalURL)
+	}
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+type SQLiteClicksRepo struct {
+	db        *sql.DB
+	tableName string
+	timeout   time.Duration
+}
+
+// NewSQLiteClicksRepo stores clicks in tableName, sharing the database
+// and query timeout of the links repository.
+func NewSQLiteClicksRepo(links *SQLiteLinksRepo, tableName string) (*SQLiteClicksRepo, error) {
+	if err := migrateClicksSchema(links.db, tableName); err != nil {
+		return nil, fmt.Errorf("failed to migrate clicks schema: %w", err)
+	}
+
+	return &SQLiteClicksRepo{db: links.db, tableName: tableName, timeout: links.timeout}, nil
+}
+
+func migrateClicksSchema(db *sql.DB, tableName string) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			short_link TEXT NOT NULL,
+			clicked_at INTEGER NOT NULL,
+			referrer TEXT NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			ip TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_%[1]s_short_link_clicked_at ON %[1]s (short_link, clicked_at);
+	`, tableName)
+
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("error executing migration: %w", err)
+	}
+	return nil
+}
+
+func (p *SQLiteClicksRepo) AddClicks(ctx context.Context, clicks []domain.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	values := make([]string, 0, len(clicks))
+	args := make([]any, 0, 5*len(clicks))
+	for _, click := range clicks {
+		values = append(values, "(?, ?, ?, ?, ?)")
+		args = append(args, click.ShortLink, click.At.UnixMilli(), click.Referrer, click.UserAgent, click.IP)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (short_link, clicked_at, referrer, user_agent, ip)
+		VALUES %s;
+	`, p.tableName, strings.Join(values, ", "))
+
+	if _, err := p.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("error adding clicks to SQLite: %w", err)
+	}
+	return nil
+}
+
+func (p *SQLiteClicksRepo) CountClicks(ctx context.Context, shortLink string, from, to time.Time, bucket time.Duration) ([]domain.ClickBucket, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	// Buckets are aligned to the Unix epoch like date_bin on Postgres.
+	query := fmt.Sprintf(`
+		SELECT clicked_at - clicked_at %% ?4 AS bucket, COUNT(*)
+		FROM %s
+		WHERE short_link = ?1 AND clicked_at >= ?2 AND clicked_at < ?3
+		GROUP BY bucket
+		ORDER BY bucket;
+	`, p.tableName)
+
+	rows, err := p.db.QueryContext(ctx, query, shortLink, from.UnixMilli(), to.UnixMilli(), bucket.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("error counting clicks: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []domain.ClickBucket
+	for rows.Next() {
+		var (
+			start int64
+			b     domain.ClickBucket
+		)
+		if err := rows.Scan(&start, &b.Count); err != nil {
+			return nil, fmt.Errorf("error reading clicks: %w", err)
+		}
+		b.Start = time.UnixMilli(start).UTC()
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading clicks: %w", err)
+	}
+
+	return buckets, nil
+}
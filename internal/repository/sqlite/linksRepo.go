@@ -0,0 +1,280 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	driver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type SQLiteLinksRepo struct {
+	db        *sql.DB
+	tableName string
+	timeout   time.Duration
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// NewSQLiteLinksRepo opens the database file at path in WAL mode, creating it if
+// needed, and prepares the links table. Every query is bounded by timeout.
+func NewSQLiteLinksRepo(path, tableName string, timeout time.Duration) (*SQLiteLinksRepo, error) {
+	db, err := openDB(path, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite: %w", err)
+	}
+
+	if err := migrateSchema(db, tableName); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	return &SQLiteLinksRepo{db: db, tableName: tableName, timeout: timeout}, nil
+}
+
+// Close closes the database, which is shared with the clicks repository.
+func (p *SQLiteLinksRepo) Close() error {
+	return p.db.Close()
+}
+
+// HealthCheck reports whether the database file can be queried.
+func (p *SQLiteLinksRepo) HealthCheck(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+// withTimeout bounds a single repository call by the configured query timeout.
+func (p *SQLiteLinksRepo) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, p.timeout)
+}
+
+func openDB(path string, timeout time.Duration) (*sql.DB, error) {
+	// Every pooled connection applies the pragmas. Writers wait up to the query timeout
+	// for the database lock and transactions take it upfront instead of on the first write.
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", timeout.Milliseconds()))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_pragma", "foreign_keys(ON)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("error opening SQLite: %w", err)
+	}
+
+	// sql.Open does not open the file, make sure it is usable before serving.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error opening SQLite database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+func migrateSchema(db *sql.DB, tableName string) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			short_link TEXT NOT NULL UNIQUE,
+			original_url TEXT NOT NULL UNIQUE,
+			expires_at INTEGER,
+			disabled INTEGER NOT NULL DEFAULT 0
+		);
+	`, tableName)
+
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("error executing migration: %w", err)
+	}
+	return nil
+}
+
+// Add stores the link unless its original URL is already shortened. An expired
+// link for the same URL is revived with the deadline of the new one.
+func (p *SQLiteLinksRepo) Add(ctx context.Context, link domain.Link) (string, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	// The existing short link of a URL is read by a second statement, the transaction
+	// keeps a concurrent delete from landing in between.
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error adding link to SQLite: %w", err)
+	}
+	defer tx.Rollback()
+
+	shortLink, err := p.add(ctx, tx, link)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error adding link to SQLite: %w", err)
+	}
+	return shortLink, nil
+}
+
+// AddBatch adds all links in a single transaction. Links whose original URL is
+// already stored get the existing short link, links whose short link is taken by another
+// URL fail with repository.ErrShortURLExists.
+func (p *SQLiteLinksRepo) AddBatch(ctx context.Context, links []domain.Link) ([]repository.AddResult, error) {
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error adding links batch to SQLite: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A failed statement only rolls back itself, the rest of the batch is kept.
+	results := make([]repository.AddResult, len(links))
+	for i, link := range links {
+		shortLink, err := p.add(ctx, tx, link)
+		if err != nil && !errors.Is(err, repository.ErrShortURLExists) {
+			return nil, err
+		}
+		results[i] = repository.AddResult{ShortLink: shortLink, Err: err}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error adding links batch to SQLite: %w", err)
+	}
+	return results, nil
+}
+
+func (p *SQLiteLinksRepo) add(ctx context.Context, q queryer, link domain.Link) (string, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (short_link, original_url, expires_at)
+		VALUES (?1, ?2, ?3)
+		ON CONFLICT (original_url) DO UPDATE SET expires_at = excluded.expires_at
+		WHERE %[1]s.expires_at IS NOT NULL AND %[1]s.expires_at <= ?4
+		RETURNING short_link;
+	`, p.tableName)
+
+	var shortLink string
+	err := q.QueryRowContext(ctx, query, link.ShortLink, link.OriginalURL, toMillis(link.ExpiresAt), time.Now().UnixMilli()).Scan(&shortLink)
+	if err != nil {
+		return p.handleAddError(ctx, q, err, link.OriginalURL)
+	}
+
+	return shortLink, nil
+}
+
+func (p *SQLiteLinksRepo) handleAddError(ctx context.Context, q queryer, err error, originalURL string) (string, error) {
+	if err == sql.ErrNoRows {
+		return p.retrieveShortLink(ctx, q, originalURL)
+	}
+
+	var sqliteErr *driver.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return "", repository.ErrShortURLExists
+	}
+
+	return "", fmt.Errorf("error adding link to SQLite: %w", err)
+}
+
+func (p *SQLiteLinksRepo) retrieveShortLink(ctx context.Context, q queryer, originalURL string) (string, error) {
+	query := fmt.Sprintf(`
+		SELECT short_link FROM %s WHERE original_url = ?1;
+	`, p.tableName)
+
+	var shortLink string
+	if err := q.QueryRowContext(ctx, query, originalURL).Scan(&shortLink); err != nil {
+		return "", fmt.Errorf("error retrieving short link: %w", err)
+	}
+
+	return shortLink, nil
+}
+
+func (p *SQLiteLinksRepo) GetByShortLink(ctx context.Context, shortLink string) (*domain.Link, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		SELECT original_url, expires_at, disabled FROM %s WHERE short_link = ?1;
+	`, p.tableName)
+
+	var (
+		originalURL string
+		expiresAt   sql.NullInt64
+		disabled    bool
+	)
+	err := p.db.QueryRowContext(ctx, query, shortLink).Scan(&originalURL, &expiresAt, &disabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrShortURLNotFound
+		}
+		return nil, fmt.Errorf("error retrieving original URL: %w", err)
+	}
+
+	link := &domain.Link{ShortLink: shortLink, OriginalURL: originalURL, Disabled: disabled}
+	if expiresAt.Valid {
+		t := time.UnixMilli(expiresAt.Int64).UTC()
+		link.ExpiresAt = &t
+	}
+	return link, nil
+}
+
+func (p *SQLiteLinksRepo) Delete(ctx context.Context, shortLink string) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		DELETE FROM %s WHERE short_link = ?1;
+	`, p.tableName)
+
+	res, err := p.db.ExecContext(ctx, query, shortLink)
+	if err != nil {
+		return fmt.Errorf("error deleting link: %w", err)
+	}
+	return checkAffected(res)
+}
+
+func (p *SQLiteLinksRepo) SetDisabled(ctx context.Context, shortLink string, disabled bool) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET disabled = ?2 WHERE short_link = ?1;
+	`, p.tableName)
+
+	res, err := p.db.ExecContext(ctx, query, shortLink, disabled)
+	if err != nil {
+		return fmt.Errorf("error updating link: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// checkAffected maps a statement that touched no rows to repository.ErrShortURLNotFound.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error reading affected rows: %w", err)
+	}
+	if n == 0 {
+		return repository.ErrShortURLNotFound
+	}
+	return nil
+}
+
+// toMillis stores times as Unix milliseconds, which SQLite compares and buckets as integers.
+func toMillis(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UnixMilli()
+}
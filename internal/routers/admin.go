@@ -0,0 +1,31 @@
+package routers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	resp "url-shortener/internal/lib/api/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminOnly lets through requests carrying "Authorization: Bearer <token>". Without a
+// configured token the routes it guards are disabled, so links cannot be changed by anyone
+// who reaches the service.
+func adminOnly(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, resp.Forbidden("admin routes are disabled, set APP_ADMIN_TOKEN"))
+			return
+		}
+
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, resp.Unauthorized("invalid admin token"))
+			return
+		}
+		c.Next()
+	}
+}
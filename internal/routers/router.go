@@ -4,7 +4,13 @@ import (
 	"log/slog"
 
 	_ "url-shortener/docs"
+	"url-shortener/internal/config"
+	healthHandlers "url-shortener/internal/handlers/health"
+	"url-shortener/internal/handlers/redirect"
+	"url-shortener/internal/handlers/stats"
 	"url-shortener/internal/handlers/url"
+	"url-shortener/internal/health"
+	"url-shortener/internal/metrics"
 	"url-shortener/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -13,24 +19,45 @@ import (
 )
 
 // InitRouter initialize routing information
-func InitRouter(log *slog.Logger, urlService *services.LinkService) *gin.Engine {
+func InitRouter(log *slog.Logger, urlService services.Shortener, analyticsService *services.AnalyticsService, healthRegistry *health.Registry, m *metrics.Metrics, appCfg config.AppConfig) *gin.Engine {
 	r := gin.New()
 	// Connect middlewares
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
+	r.Use(m.Middleware())
 	r.GET(
 		"/swagger/*any",
 		ginSwagger.WrapHandler(swaggerFiles.Handler),
 	)
 
+	healthHandler := healthHandlers.NewHealthHandler(log, healthRegistry)
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+	r.GET("/metrics", gin.WrapH(m.Handler()))
+
 	apiv1 := r.Group("/api/v1")
-	linksHandler := url.NewLinkHandler(log, urlService)
+	linksHandler := url.NewLinkHandler(log, urlService, appCfg.BatchMaxSize)
+
+	statsHandler := stats.NewStatsHandler(log, analyticsService)
 
 	link := apiv1.Group("/link")
 	{
 		link.POST("/", linksHandler.SaveLink)
+		link.POST("/batch", linksHandler.SaveBatch)
 		link.GET("/:link", linksHandler.GetLink)
+		link.GET("/:link/stats", statsHandler.GetStats)
+	}
+
+	admin := link.Group("", adminOnly(appCfg.AdminToken))
+	{
+		admin.DELETE("/:link", linksHandler.DeleteLink)
+		admin.POST("/:link/disable", linksHandler.DisableLink)
+		admin.POST("/:link/enable", linksHandler.EnableLink)
 	}
 
+	redirectHandler := redirect.NewRedirectHandler(log, urlService, analyticsService, appCfg.RedirectCode)
+	r.GET("/:code", redirectHandler.Redirect)
+	r.HEAD("/:code", redirectHandler.Redirect)
+
 	return r
 }
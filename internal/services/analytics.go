@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// MaxStatsBuckets limits the size of a histogram returned by AnalyticsService.Stats.
+const MaxStatsBuckets = 1000
+
+// ClickStats holds the clicks of a link in a time range.
+type ClickStats struct {
+	Total   int64
+	Buckets []domain.ClickBucket
+}
+
+// AnalyticsService records clicks asynchronously and answers click statistics.
+// Clicks are queued in memory and written in batches, so recording never waits
+// for the repository. Clicks that do not fit into the queue are dropped.
+type AnalyticsService struct {
+	clicks        repository.ClicksRepo
+	links         repository.LinksRepo
+	queue         chan domain.Click
+	batchSize     int
+	flushInterval time.Duration
+	dropped       atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+func NewAnalyticsService(clicks repository.ClicksRepo, links repository.LinksRepo, bufferSize, batchSize int, flushInterval time.Duration) (*AnalyticsService, error) {
+	if bufferSize <= 0 || batchSize <= 0 || flushInterval <= 0 {
+		return nil, ErrInvalidAnalyticsConfig
+	}
+
+	s := &AnalyticsService{
+		clicks:        clicks,
+		links:         links,
+		queue:         make(chan domain.Click, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+	go s.run()
+
+	return s, nil
+}
+
+// Record queues the click without blocking.
+func (s *AnalyticsService) Record(click domain.Click) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- click:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of clicks lost because the queue was full.
+func (s *AnalyticsService) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close stops accepting clicks and waits until the queued ones are written.
+func (s *AnalyticsService) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+}
+
+func (s *AnalyticsService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.Click, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		// Clicks are written after their requests are gone, so they are not bound to any request context.
+		if err := s.clicks.AddClicks(context.Background(), batch); err != nil {
+			log.Default().Printf("Failed to save %d clicks: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case click, ok := <-s.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Stats returns the clicks of the link in [from, to) as a histogram with one
+// entry per bucket, including empty ones.
+func (s *AnalyticsService) Stats(ctx context.Context, shortLink string, from, to time.Time, bucket time.Duration) (*ClickStats, error) {
+	if bucket < time.Second || !from.Before(to) {
+		return nil, ErrInvalidStatsRange
+	}
+
+	width := int64(bucket / time.Second)
+	first := from.Unix() - from.Unix()%width
+	last := to.Unix()
+	if (last-first+width-1)/width > MaxStatsBuckets {
+		return nil, ErrInvalidStatsRange
+	}
+
+	if _, err := s.links.GetByShortLink(ctx, shortLink); err != nil {
+		if errors.Is(err, repository.ErrShortURLNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get link '%s': %w", shortLink, err)
+	}
+
+	counted, err := s.clicks.CountClicks(ctx, shortLink, from, to, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks for '%s': %w", shortLink, err)
+	}
+
+	counts := make(map[int64]int64, len(counted))
+	for _, b := range counted {
+		counts[b.Start.Unix()] = b.Count
+	}
+
+	stats := &ClickStats{}
+	for start := first; start < last; start += width {
+		count := counts[start]
+		stats.Total += count
+		stats.Buckets = append(stats.Buckets, domain.ClickBucket{Start: time.Unix(start, 0).UTC(), Count: count})
+	}
+
+	return stats, nil
+}
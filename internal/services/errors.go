@@ -4,9 +4,21 @@ import "errors"
 
 var (
 	ErrNotFound           = errors.New("link not found")
+	ErrExpired            = errors.New("link expired")
+	ErrDisabled           = errors.New("link disabled")
+	ErrInvalidExpiry      = errors.New("expiration must be in the future")
 	ErrMaxRetriesExceeded = errors.New("max retries exceeded")
 	ErrInvalidHost        = errors.New("invalid host domain")
 	ErrInvalidLinkSize    = errors.New("invalid link size")
 	ErrInvalidLink        = errors.New("invalid link")
 	ErrInvalidURL         = errors.New("invalid url")
+	ErrInvalidAlias       = errors.New("invalid alias")
+	ErrReservedAlias      = errors.New("alias is reserved")
+	ErrAliasTaken         = errors.New("alias is already taken")
+	ErrURLExists          = errors.New("url already has a short link")
+	ErrExpiryConflict     = errors.New("url already has a short link with another expiration")
+	ErrInvalidStyle       = errors.New("unknown short link style")
+
+	ErrInvalidStatsRange      = errors.New("invalid stats range")
+	ErrInvalidAnalyticsConfig = errors.New("invalid analytics configuration")
 )
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/lib/generator"
+	"url-shortener/internal/repository"
+)
+
+var ErrInvalidKeyPoolConfig = errors.New("invalid key pool configuration")
+
+// KeyPoolConfig describes how the pool is kept filled. Keys of KeySize characters are leased
+// LeaseSize at a time, and whenever the shared pool holds fewer than LowWater keys, RefillSize
+// new keys are added.
+type KeyPoolConfig struct {
+	KeySize       int
+	LeaseSize     int
+	LowWater      int
+	RefillSize    int
+	CheckInterval time.Duration
+}
+
+// KeyPool is a generator handing out short links pre-generated into a KeysRepo. Keys
+// are produced by another generator and checked against the stored links in the background,
+// so saving a link rarely collides. Every instance leases its own batch of keys, the keys
+// leased but not used before the instance stops are lost. Short links of another size than
+// KeySize, e.g. after the length grew, are generated by the source generator directly.
+type KeyPool struct {
+	keys   repository.KeysRepo
+	source generator.Generator
+	cfg    KeyPoolConfig
+
+	mu      sync.Mutex
+	leased  []string
+	leasing chan struct{} // Closed when the running lease finishes, nil if none runs
+
+	depth  atomic.Int64
+	refill chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewKeyPool(keys repository.KeysRepo, source generator.Generator, cfg KeyPoolConfig) (*KeyPool, error) {
+	if cfg.KeySize <= 0 || cfg.LeaseSize <= 0 || cfg.LowWater < 0 || cfg.RefillSize <= 0 || cfg.CheckInterval <= 0 {
+		return nil, ErrInvalidKeyPoolConfig
+	}
+
+	p := &KeyPool{
+		keys:   keys,
+		source: source,
+		cfg:    cfg,
+		refill: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+
+	return p, nil
+}
+
+// Generate hands out a leased key, leasing a new batch when none is left. If the shared
+// pool is empty as well, the key is generated by the source generator directly.
+func (p *KeyPool) Generate(ctx context.Context, req generator.Request) (string, error) {
+	if req.Size != p.cfg.KeySize {
+		return p.source.Generate(ctx, req)
+	}
+
+	key, ok, err := p.take(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		log.Default().Printf("Key pool is empty, generating a short link directly")
+		return p.source.Generate(ctx, req)
+	}
+	return key, nil
+}
+
+// take returns a leased key. Leasing is a round trip to the storage, so it runs without
+// holding the lock and a single lease at a time serves the requests waiting for it.
+func (p *KeyPool) take(ctx context.Context) (string, bool, error) {
+	p.mu.Lock()
+	if key, ok := p.pop(); ok {
+		p.mu.Unlock()
+		return key, true, nil
+	}
+
+	if wait := p.leasing; wait != nil {
+		p.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		}
+		// Another request leased the keys, an empty lease is not retried right away.
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		key, ok := p.pop()
+		return key, ok, nil
+	}
+
+	done := make(chan struct{})
+	p.leasing = done
+	p.mu.Unlock()
+
+	keys, err := p.keys.LeaseKeys(ctx, p.cfg.LeaseSize)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.leasing = nil
+	close(done)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to lease keys: %w", err)
+	}
+	p.leased = append(p.leased, keys...)
+	p.depth.Add(-int64(len(keys)))
+	p.requestRefill()
+
+	key, ok := p.pop()
+	return key, ok, nil
+}
+
+func (p *KeyPool) pop() (string, bool) {
+	if len(p.leased) == 0 {
+		return "", false
+	}
+	key := p.leased[len(p.leased)-1]
+	p.leased = p.leased[:len(p.leased)-1]
+	return key, true
+}
+
+// Canonical, Valid, ReportCollision and Stats are those of the source generator, so the pool
+// can replace it.
+
+func (p *KeyPool) Canonical(code string) string {
+	if c, ok := p.source.(generator.Canonicalizer); ok {
+		return c.Canonical(code)
+	}
+	return code
+}
+
+func (p *KeyPool) Valid(code string) bool {
+	v, ok := p.source.(generator.Validator)
+	return ok && v.Valid(code)
+}
+
+func (p *KeyPool) ReportCollision() {
+	if r, ok := p.source.(generator.CollisionReporter); ok {
+		r.ReportCollision()
+	}
+}
+
+func (p *KeyPool) Stats() generator.Stats {
+	if r, ok := p.source.(generator.CollisionReporter); ok {
+		return r.Stats()
+	}
+	return generator.Stats{}
+}
+
+// Unwrap returns the source generator.
+func (p *KeyPool) Unwrap() generator.Generator {
+	return p.source
+}
+
+// Depth returns the number of keys in the shared pool as of the last check.
+func (p *KeyPool) Depth() int64 {
+	return p.depth.Load()
+}
+
+// Leased returns the number of keys leased by this instance and not handed out yet.
+func (p *KeyPool) Leased() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.leased)
+}
+
+// Close stops refilling the pool and waits for a running refill to finish.
+func (p *KeyPool) Close() error {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+	return nil
+}
+
+func (p *KeyPool) requestRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+func (p *KeyPool) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	p.fill()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.fill()
+		case <-p.refill:
+			p.fill()
+		}
+	}
+}
+
+// fill tops the shared pool up when it runs below the low-water mark. Refills run in the
+// background and are not bound to any request context.
+func (p *KeyPool) fill() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	depth, err := p.keys.CountKeys(ctx)
+	if err != nil {
+		log.Default().Printf("Failed to count pooled keys: %v", err)
+		return
+	}
+	p.depth.Store(int64(depth))
+	if depth >= p.cfg.LowWater {
+		return
+	}
+
+	keys := make([]string, 0, p.cfg.RefillSize)
+	for len(keys) < p.cfg.RefillSize {
+		key, err := p.source.Generate(ctx, generator.Request{Size: p.cfg.KeySize})
+		if err != nil {
+			log.Default().Printf("Failed to generate pooled keys: %v", err)
+			return
+		}
+		keys = append(keys, key)
+	}
+
+	added, err := p.keys.AddKeys(ctx, keys)
+	if err != nil {
+		log.Default().Printf("Failed to add pooled keys: %v", err)
+		return
+	}
+	p.depth.Add(int64(added))
+	log.Default().Printf("Refilled key pool with %d keys, %d skipped as taken", added, len(keys)-added)
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"log"
+	"sync"
+)
+
+// LengthPolicy describes the lengths of generated short links. Generation starts at Initial
+// and any length in [Min, Max] is accepted when resolving. Once the share of collided attempts
+// over the last GrowthWindow attempts exceeds GrowthThreshold, generated links grow by one
+// character, up to Max. A non-positive GrowthThreshold disables growth.
+type LengthPolicy struct {
+	Initial         int
+	Min             int
+	Max             int
+	GrowthThreshold float64
+	GrowthWindow    int
+}
+
+func (p LengthPolicy) valid() bool {
+	return p.Min > 0 && p.Min <= p.Initial && p.Initial <= p.Max &&
+		(p.GrowthThreshold <= 0 || p.GrowthWindow > 0)
+}
+
+// linkLength tracks the current generated length and the collision rate of the current window.
+type linkLength struct {
+	mu         sync.Mutex
+	policy     LengthPolicy
+	current    int
+	attempts   int
+	collisions int
+}
+
+func newLinkLength(policy LengthPolicy) *linkLength {
+	return &linkLength{policy: policy, current: policy.Initial}
+}
+
+func (l *linkLength) get() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+// observe records the outcome of attempts to store generated links of the given length.
+// Attempts made with a length that is no longer current are ignored.
+func (l *linkLength) observe(length, attempts, collisions int) {
+	if l.policy.GrowthThreshold <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if length != l.current {
+		return
+	}
+	l.attempts += attempts
+	l.collisions += collisions
+	if l.attempts < l.policy.GrowthWindow {
+		return
+	}
+
+	rate := float64(l.collisions) / float64(l.attempts)
+	if rate > l.policy.GrowthThreshold && l.current < l.policy.Max {
+		l.current++
+		log.Default().Printf("Collision rate %.3f exceeded %.3f, growing short links to %d characters", rate, l.policy.GrowthThreshold, l.current)
+	}
+	l.attempts, l.collisions = 0, 0
+}
@@ -1,10 +1,14 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"url-shortener/internal/cache"
 	"url-shortener/internal/domain"
@@ -14,8 +18,8 @@ import (
 	linkDomain "github.com/chmike/domain"
 )
 
-func isValidShortLink(shortLink string, linkSize int, alphabetSet map[rune]bool) bool {
-	if len(shortLink) != linkSize {
+func isValidShortLink(shortLink string, minSize, maxSize int, alphabetSet map[rune]bool) bool {
+	if len(shortLink) < minSize || len(shortLink) > maxSize {
 		return false
 	}
 	for _, ch := range shortLink {
@@ -31,20 +35,58 @@ func isValidURL(str string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
+// AliasPolicy describes which custom aliases may be requested instead of a generated short link.
+type AliasPolicy struct {
+	MinLength int
+	MaxLength int
+	Reserved  []string
+}
+
+// DefaultStyle selects the generator the service was created with.
+const DefaultStyle = "default"
+
+// SaveOptions holds the optional parameters of a new short link. Style picks a generator
+// registered with RegisterStyle, the default generator is used when it is empty.
+type SaveOptions struct {
+	Alias     string
+	ExpiresAt *time.Time
+	Style     string
+}
+
+// Shortener is the set of link operations served over HTTP. It is implemented by
+// LinkService and allows decorating it, e.g. with metrics.
+type Shortener interface {
+	Save(ctx context.Context, originalURL string, opts SaveOptions, retries int) (string, error)
+	SaveBatch(ctx context.Context, originalURLs []string, retries int) ([]BatchResult, error)
+	GetOriginalURL(ctx context.Context, shortLink string) (string, error)
+	Delete(ctx context.Context, shortLink string) error
+	SetDisabled(ctx context.Context, shortLink string, disabled bool) error
+}
+
+var _ Shortener = (*LinkService)(nil)
+
 type LinkService struct {
 	repo        repository.LinksRepo
 	cache       cache.Cache
+	probeCache  cache.Cache
 	generator   generator.Generator
-	linkSize    int
+	styles      map[string]generator.Generator
+	length      *linkLength
 	alphabetSet map[rune]bool
+	aliasPolicy AliasPolicy
+	reserved    map[string]bool
 	host        string
+	collisions  atomic.Int64
 }
 
-func NewLinkService(r repository.LinksRepo, c cache.Cache, g generator.Generator, linkAlphabet string, linkSize int, host string) (*LinkService, error) {
+func NewLinkService(r repository.LinksRepo, c cache.Cache, g generator.Generator, linkAlphabet string, lengthPolicy LengthPolicy, host string, aliasPolicy AliasPolicy) (*LinkService, error) {
 	if err := linkDomain.Check(host); err != nil {
 		return nil, ErrInvalidHost
 	}
-	if linkSize <= 0 {
+	if !lengthPolicy.valid() {
+		return nil, ErrInvalidLinkSize
+	}
+	if aliasPolicy.MinLength <= 0 || aliasPolicy.MinLength > aliasPolicy.MaxLength {
 		return nil, ErrInvalidLinkSize
 	}
 
@@ -53,58 +95,302 @@ func NewLinkService(r repository.LinksRepo, c cache.Cache, g generator.Generator
 		alphabetSet[ch] = true
 	}
 
+	reserved := make(map[string]bool, len(aliasPolicy.Reserved))
+	for _, alias := range aliasPolicy.Reserved {
+		reserved[strings.ToLower(alias)] = true
+	}
+
 	return &LinkService{
 		repo:        r,
 		cache:       c,
+		probeCache:  cache.Unwrap(c),
 		generator:   g,
-		linkSize:    linkSize,
+		styles:      make(map[string]generator.Generator),
+		length:      newLinkLength(lengthPolicy),
 		host:        host,
 		alphabetSet: alphabetSet,
+		aliasPolicy: aliasPolicy,
+		reserved:    reserved,
 	}, nil
 }
 
-func (s *LinkService) Save(originalURL string, retries int) (string, error) {
+// RegisterStyle makes the generator selectable per link with SaveOptions.Style. Its short links
+// are accepted by the service when the generator implements generator.Validator.
+// It must be called before the service is used.
+func (s *LinkService) RegisterStyle(style string, g generator.Generator) {
+	s.styles[style] = g
+}
+
+func (s *LinkService) Save(ctx context.Context, originalURL string, opts SaveOptions, retries int) (string, error) {
 	if !isValidURL(originalURL) {
 		return "", ErrInvalidURL
 	}
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return "", ErrInvalidExpiry
+	}
+	gen := s.generator
+	if opts.Style != "" && opts.Style != DefaultStyle {
+		styled, ok := s.styles[opts.Style]
+		if !ok {
+			return "", ErrInvalidStyle
+		}
+		gen = styled
+	}
 
 	shortURL := url.URL{Scheme: "https", Host: s.host}
 	logger := log.Default()
 
+	if opts.Alias != "" {
+		return s.saveAlias(ctx, domain.Link{ShortLink: opts.Alias, OriginalURL: originalURL, ExpiresAt: opts.ExpiresAt}, shortURL)
+	}
+
+	// The cache does not know the lifetime of a link, a requested one is checked against the repository.
+	if shortLink, ok := s.lookupDeterministic(ctx, gen, originalURL); ok && opts.ExpiresAt == nil {
+		logger.Printf("Link %s is already shortened to %s", originalURL, shortLink)
+		shortURL.Path = shortLink
+		return shortURL.String(), nil
+	}
+
+	// Collision-free generators can still hit a custom alias of the same length, hence the retries.
 	for i := 0; i < retries; i++ {
+		size := s.length.get()
+		code, err := gen.Generate(ctx, generator.Request{OriginalURL: originalURL, Size: size, Attempt: i})
+		if err != nil {
+			return "", fmt.Errorf("failed to generate short link: %w", err)
+		}
 		newLink := domain.Link{
-			ShortLink:   s.generator.Generate(s.linkSize),
+			ShortLink:   code,
 			OriginalURL: originalURL,
+			ExpiresAt:   opts.ExpiresAt,
 		}
 
-		shortLink, err := s.repo.Add(newLink)
+		shortLink, err := s.repo.Add(ctx, newLink)
 		if err == nil {
 			logger.Printf("Successfully saved link %s with short link %s", originalURL, shortLink)
-			return s.saveToCacheAndReturnURL(shortLink, originalURL, shortURL)
+			s.observeLength(gen, size, 1, 0)
+			if shortLink != newLink.ShortLink {
+				// The URL was already shortened, a requested lifetime must match the stored one.
+				if err := s.checkExpiry(ctx, shortLink, opts.ExpiresAt); err != nil {
+					return "", err
+				}
+				shortURL.Path = shortLink
+				return shortURL.String(), nil
+			}
+			return s.saveToCacheAndReturnURL(ctx, newLink, shortURL)
 		}
 
 		if errors.Is(err, repository.ErrShortURLExists) {
 			logger.Printf("Short link collision occurred: %s", newLink.ShortLink)
+			s.reportCollisions(gen, 1)
+			s.observeLength(gen, size, 1, 1)
 			continue
 		}
 
 		logger.Printf("Failed to save short link: %v", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
 	}
 
 	return "", ErrMaxRetriesExceeded
 }
 
-func (s *LinkService) GetOriginalURL(shortLink string) (string, error) {
-	if !isValidShortLink(shortLink, s.linkSize, s.alphabetSet) {
+// checkExpiry fails with ErrExpiryConflict when an expiration is requested for a URL whose
+// existing short link expires at another time or never. Storages keep at least milliseconds.
+func (s *LinkService) checkExpiry(ctx context.Context, shortLink string, expiresAt *time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	link, err := s.repo.GetByShortLink(ctx, shortLink)
+	if err != nil {
+		return fmt.Errorf("failed to check expiration of '%s': %w", shortLink, err)
+	}
+	if link.ExpiresAt == nil || !link.ExpiresAt.Truncate(time.Millisecond).Equal(expiresAt.Truncate(time.Millisecond)) {
+		return ErrExpiryConflict
+	}
+	return nil
+}
+
+// lookupDeterministic checks whether a deterministic generator's first short link for the URL
+// is cached with the same URL, in which case the link exists and the repository is not queried.
+func (s *LinkService) lookupDeterministic(ctx context.Context, gen generator.Generator, originalURL string) (string, bool) {
+	if s.cache == nil {
+		return "", false
+	}
+	if d, ok := gen.(generator.Deterministic); !ok || !d.Deterministic() {
+		return "", false
+	}
+
+	shortLink, err := gen.Generate(ctx, generator.Request{OriginalURL: originalURL, Size: s.length.get()})
+	if err != nil {
+		return "", false
+	}
+	// Probe past the cache decorators, a miss here is not a missed redirect
+	cachedURL, err := s.probeCache.Get(ctx, shortLink)
+	return shortLink, err == nil && cachedURL == originalURL
+}
+
+// LinkLength returns the current length of generated short links.
+func (s *LinkService) LinkLength() int {
+	return s.length.get()
+}
+
+// observeLength feeds the length policy with attempts of the default generator only,
+// styled generators do not follow it.
+func (s *LinkService) observeLength(gen generator.Generator, size, attempts, collisions int) {
+	if gen == s.generator {
+		s.length.observe(size, attempts, collisions)
+	}
+}
+
+// Collisions returns the number of generated short links that were taken and retried.
+func (s *LinkService) Collisions() int64 {
+	return s.collisions.Load()
+}
+
+// reportCollisions counts the retried short links and feeds the collision statistics of
+// generators that keep them.
+func (s *LinkService) reportCollisions(gen generator.Generator, n int) {
+	s.collisions.Add(int64(n))
+	if r, ok := gen.(generator.CollisionReporter); ok {
+		for i := 0; i < n; i++ {
+			r.ReportCollision()
+		}
+	}
+}
+
+// BatchResult is the outcome of shortening a single URL of a batch.
+type BatchResult struct {
+	ShortURL string
+	Err      error
+}
+
+// SaveBatch shortens every URL and returns the results in input order. Invalid URLs
+// and exhausted retries fail only their own item. Batch results are not pre-cached.
+func (s *LinkService) SaveBatch(ctx context.Context, originalURLs []string, retries int) ([]BatchResult, error) {
+	results := make([]BatchResult, len(originalURLs))
+	pending := make([]int, 0, len(originalURLs))
+	for i, originalURL := range originalURLs {
+		if !isValidURL(originalURL) {
+			results[i].Err = ErrInvalidURL
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	shortURL := url.URL{Scheme: "https", Host: s.host}
+	logger := log.Default()
+
+	for attempt := 0; attempt < retries && len(pending) > 0; attempt++ {
+		size := s.length.get()
+		links := make([]domain.Link, len(pending))
+		for j, i := range pending {
+			code, err := s.generator.Generate(ctx, generator.Request{OriginalURL: originalURLs[i], Size: size, Attempt: attempt})
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate short link: %w", err)
+			}
+			links[j] = domain.Link{ShortLink: code, OriginalURL: originalURLs[i]}
+		}
+
+		added, err := s.repo.AddBatch(ctx, links)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save links batch: %w", err)
+		}
+
+		var collided []int
+		for j, i := range pending {
+			switch {
+			case added[j].Err == nil:
+				shortURL.Path = added[j].ShortLink
+				results[i].ShortURL = shortURL.String()
+			case errors.Is(added[j].Err, repository.ErrShortURLExists):
+				collided = append(collided, i)
+			default:
+				results[i].Err = fmt.Errorf("failed to save link '%s': %w", originalURLs[i], added[j].Err)
+			}
+		}
+
+		if len(collided) > 0 {
+			logger.Printf("Short link collisions in batch: %d of %d", len(collided), len(pending))
+			s.reportCollisions(s.generator, len(collided))
+		}
+		s.length.observe(size, len(pending), len(collided))
+		pending = collided
+	}
+
+	for _, i := range pending {
+		results[i].Err = ErrMaxRetriesExceeded
+	}
+
+	return results, nil
+}
+
+// saveAlias stores the link under the requested alias instead of a generated short link.
+func (s *LinkService) saveAlias(ctx context.Context, link domain.Link, shortURL url.URL) (string, error) {
+	alias := link.ShortLink
+	if !isValidShortLink(alias, s.aliasPolicy.MinLength, s.aliasPolicy.MaxLength, s.alphabetSet) {
+		return "", ErrInvalidAlias
+	}
+	if s.reserved[strings.ToLower(alias)] {
+		return "", ErrReservedAlias
+	}
+
+	shortLink, err := s.repo.Add(ctx, link)
+	if errors.Is(err, repository.ErrShortURLExists) {
+		return "", ErrAliasTaken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to save alias '%s': %w", alias, err)
+	}
+	if shortLink != alias {
+		// The URL was shortened before and the repository deduplicated it.
+		return "", ErrURLExists
+	}
+
+	log.Default().Printf("Successfully saved link %s with alias %s", link.OriginalURL, alias)
+	return s.saveToCacheAndReturnURL(ctx, link, shortURL)
+}
+
+// isValidCode reports whether the code may be a generated short link, a custom alias
+// or a short link of one of the registered styles.
+func (s *LinkService) isValidCode(shortLink string) bool {
+	if isValidShortLink(shortLink, s.length.policy.Min, s.length.policy.Max, s.alphabetSet) ||
+		isValidShortLink(shortLink, s.aliasPolicy.MinLength, s.aliasPolicy.MaxLength, s.alphabetSet) {
+		return true
+	}
+	for _, g := range s.styles {
+		if v, ok := g.(generator.Validator); ok && v.Valid(shortLink) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOriginalURL resolves the short link. When the generator accepts look-alike characters,
+// an unknown short link is retried in its canonical form.
+func (s *LinkService) GetOriginalURL(ctx context.Context, shortLink string) (string, error) {
+	if !s.isValidCode(shortLink) {
 		return "", ErrInvalidLink
 	}
 
+	originalURL, err := s.resolve(ctx, shortLink)
+	if errors.Is(err, ErrNotFound) {
+		if c, ok := s.generator.(generator.Canonicalizer); ok {
+			if canonical := c.Canonical(shortLink); canonical != shortLink {
+				return s.resolve(ctx, canonical)
+			}
+		}
+	}
+	return originalURL, err
+}
+
+func (s *LinkService) resolve(ctx context.Context, shortLink string) (string, error) {
 	logger := log.Default()
 	logger.Printf("Fetching original URL for short link: %s", shortLink)
 
 	// Check cache first
 	if s.cache != nil {
-		if originalURL, err := s.cache.Get(shortLink); err == nil {
+		if originalURL, err := s.cache.Get(ctx, shortLink); err == nil {
 			logger.Printf("Found in cache: %s", originalURL)
 			return originalURL, nil
 		}
@@ -112,23 +398,77 @@ func (s *LinkService) GetOriginalURL(shortLink string) (string, error) {
 	}
 
 	// Check repository
-	link, err := s.repo.GetByShortLink(shortLink)
+	link, err := s.repo.GetByShortLink(ctx, shortLink)
 	if err != nil {
 		if errors.Is(err, repository.ErrShortURLNotFound) {
 			return "", ErrNotFound
 		}
 		return "", fmt.Errorf("failed to get original URL from repository for '%s': %w", shortLink, err)
 	}
+	if link.Disabled {
+		return "", ErrDisabled
+	}
+	if link.Expired(time.Now()) {
+		return "", ErrExpired
+	}
 
 	return link.OriginalURL, nil
 }
 
-func (s *LinkService) saveToCacheAndReturnURL(shortLink, originalURL string, shortURL url.URL) (string, error) {
+// Delete removes the link and evicts it from the cache.
+func (s *LinkService) Delete(ctx context.Context, shortLink string) error {
+	if !s.isValidCode(shortLink) {
+		return ErrInvalidLink
+	}
+
+	if err := s.repo.Delete(ctx, shortLink); err != nil {
+		if errors.Is(err, repository.ErrShortURLNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete link '%s': %w", shortLink, err)
+	}
+
+	log.Default().Printf("Deleted short link: %s", shortLink)
+	return s.evictFromCache(ctx, shortLink)
+}
+
+// SetDisabled enables or disables redirects for the link and evicts it from the cache.
+func (s *LinkService) SetDisabled(ctx context.Context, shortLink string, disabled bool) error {
+	if !s.isValidCode(shortLink) {
+		return ErrInvalidLink
+	}
+
+	if err := s.repo.SetDisabled(ctx, shortLink, disabled); err != nil {
+		if errors.Is(err, repository.ErrShortURLNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update link '%s': %w", shortLink, err)
+	}
+
+	log.Default().Printf("Set disabled=%t for short link: %s", disabled, shortLink)
+	return s.evictFromCache(ctx, shortLink)
+}
+
+func (s *LinkService) evictFromCache(ctx context.Context, shortLink string) error {
 	if s.cache != nil {
-		if err := s.cache.Set(shortLink, originalURL); err != nil {
-			return "", fmt.Errorf("failed to set short link in cache for '%s': %w", shortLink, err)
+		if err := s.cache.Delete(ctx, shortLink); err != nil {
+			return fmt.Errorf("failed to evict short link from cache for '%s': %w", shortLink, err)
+		}
+	}
+	return nil
+}
+
+func (s *LinkService) saveToCacheAndReturnURL(ctx context.Context, link domain.Link, shortURL url.URL) (string, error) {
+	var maxTTL time.Duration
+	if link.ExpiresAt != nil {
+		maxTTL = time.Until(*link.ExpiresAt)
+	}
+	// A link that has already run out must not get the default cache lifetime.
+	if s.cache != nil && (link.ExpiresAt == nil || maxTTL > 0) {
+		if err := s.cache.Set(ctx, link.ShortLink, link.OriginalURL, maxTTL); err != nil {
+			return "", fmt.Errorf("failed to set short link in cache for '%s': %w", link.ShortLink, err)
 		}
 	}
-	shortURL.Path = shortLink
+	shortURL.Path = link.ShortLink
 	return shortURL.String(), nil
 }
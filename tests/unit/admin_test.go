@@ -0,0 +1,83 @@
+package services_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/health"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/routers"
+	"url-shortener/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAdminTestRouter(t *testing.T, repo *MockRepo, adminToken string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cache := new(MockCache)
+	cache.On("Delete", "abcdefghij").Return(nil)
+	linkService, err := services.NewLinkService(repo, cache, &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"},
+		"abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	require.NoError(t, err)
+	analyticsService, err := services.NewAnalyticsService(memory.NewMemoryClicksRepo(), repo, 10, 10, time.Hour)
+	require.NoError(t, err)
+	t.Cleanup(analyticsService.Close)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	appCfg := config.AppConfig{RedirectCode: http.StatusFound, BatchMaxSize: 10, AdminToken: adminToken}
+	return routers.InitRouter(log, linkService, analyticsService, health.NewRegistry(time.Second), metrics.New(), appCfg)
+}
+
+func TestAdminRoutes_RequireToken(t *testing.T) {
+	repo := new(MockRepo)
+	repo.On("Delete", "abcdefghij").Return(nil).Once()
+	repo.On("SetDisabled", "abcdefghij", true).Return(nil).Once()
+	r := newAdminTestRouter(t, repo, "s3cret")
+
+	requests := []struct{ method, path string }{
+		{http.MethodDelete, "/api/v1/link/abcdefghij"},
+		{http.MethodPost, "/api/v1/link/abcdefghij/disable"},
+		{http.MethodPost, "/api/v1/link/abcdefghij/enable"},
+	}
+	for _, req := range requests {
+		for _, auth := range []string{"", "Bearer wrong", "s3cret"} {
+			w := httptest.NewRecorder()
+			httpReq := httptest.NewRequest(req.method, req.path, nil)
+			if auth != "" {
+				httpReq.Header.Set("Authorization", auth)
+			}
+			r.ServeHTTP(w, httpReq)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, "%s %s with %q", req.method, req.path, auth)
+		}
+	}
+
+	for _, req := range requests[:2] {
+		w := httptest.NewRecorder()
+		httpReq := httptest.NewRequest(req.method, req.path, nil)
+		httpReq.Header.Set("Authorization", "Bearer s3cret")
+		r.ServeHTTP(w, httpReq)
+		assert.Equal(t, http.StatusOK, w.Code, "%s %s", req.method, req.path)
+	}
+	repo.AssertExpectations(t)
+}
+
+func TestAdminRoutes_DisabledWithoutToken(t *testing.T) {
+	repo := new(MockRepo)
+	r := newAdminTestRouter(t, repo, "")
+
+	w := httptest.NewRecorder()
+	httpReq := httptest.NewRequest(http.MethodDelete, "/api/v1/link/abcdefghij", nil)
+	httpReq.Header.Set("Authorization", "Bearer ")
+	r.ServeHTTP(w, httpReq)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	repo.AssertNotCalled(t, "Delete", "abcdefghij")
+}
@@ -0,0 +1,51 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/lib/ipmask"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyticsStats_Histogram(t *testing.T) {
+	links := memory.NewMemoryLinksRepo()
+	clicks := memory.NewMemoryClicksRepo()
+	analyticsService, err := services.NewAnalyticsService(clicks, links, 100, 10, time.Hour)
+	assert.NoError(t, err)
+
+	_, err = links.Add(context.Background(), domain.Link{ShortLink: "abcdefghij", OriginalURL: "https://example.com"})
+	assert.NoError(t, err)
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, offset := range []time.Duration{10 * time.Minute, 20 * time.Minute, 2*time.Hour + time.Minute} {
+		analyticsService.Record(domain.Click{ShortLink: "abcdefghij", At: from.Add(offset)})
+	}
+	analyticsService.Record(domain.Click{ShortLink: "otherlinkk", At: from})
+	analyticsService.Close()
+
+	stats, err := analyticsService.Stats(context.Background(), "abcdefghij", from, from.Add(3*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Total)
+	assert.Equal(t, []domain.ClickBucket{
+		{Start: from, Count: 2},
+		{Start: from.Add(time.Hour), Count: 0},
+		{Start: from.Add(2 * time.Hour), Count: 1},
+	}, stats.Buckets)
+
+	_, err = analyticsService.Stats(context.Background(), "nonexisten", from, from.Add(time.Hour), time.Hour)
+	assert.ErrorIs(t, err, services.ErrNotFound)
+
+	_, err = analyticsService.Stats(context.Background(), "abcdefghij", from, from.Add(2000*time.Hour), time.Hour)
+	assert.ErrorIs(t, err, services.ErrInvalidStatsRange)
+}
+
+func TestIPMaskTruncate(t *testing.T) {
+	assert.Equal(t, "203.0.113.0", ipmask.Truncate("203.0.113.57"))
+	assert.Equal(t, "2001:db8:85a3::", ipmask.Truncate("2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	assert.Equal(t, "", ipmask.Truncate("not-an-ip"))
+}
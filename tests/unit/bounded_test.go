@@ -0,0 +1,197 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBoundedLinksRepo(t *testing.T, cfg memory.BoundedConfig, next repository.LinksRepo) *memory.BoundedLinksRepo {
+	repo, err := memory.NewBoundedLinksRepo(cfg, next)
+	require.NoError(t, err)
+	return repo
+}
+
+func addLinks(t *testing.T, repo repository.LinksRepo, shortLinks ...string) {
+	for _, shortLink := range shortLinks {
+		_, err := repo.Add(context.Background(), domain.Link{ShortLink: shortLink, OriginalURL: "https://example.com/" + shortLink})
+		require.NoError(t, err)
+	}
+}
+
+func stored(repo repository.LinksRepo, shortLink string) bool {
+	_, err := repo.GetByShortLink(context.Background(), shortLink)
+	return err == nil
+}
+
+func TestBoundedLinksRepo_LRU(t *testing.T) {
+	repo := newBoundedLinksRepo(t, memory.BoundedConfig{Policy: memory.EvictLRU, MaxEntries: 2}, nil)
+
+	addLinks(t, repo, "aaaaa", "bbbbb")
+	assert.True(t, stored(repo, "aaaaa"))
+	addLinks(t, repo, "ccccc")
+
+	assert.False(t, stored(repo, "bbbbb"))
+	assert.True(t, stored(repo, "aaaaa"))
+	assert.True(t, stored(repo, "ccccc"))
+	assert.Equal(t, 2, repo.Len())
+	assert.Equal(t, uint64(1), repo.Evictions())
+
+	// The URL index is evicted along with the link, so the URL gets a new short link
+	shortLink, err := repo.Add(context.Background(), domain.Link{ShortLink: "ddddd", OriginalURL: "https://example.com/bbbbb"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ddddd", shortLink)
+}
+
+func TestBoundedLinksRepo_LFU(t *testing.T) {
+	repo := newBoundedLinksRepo(t, memory.BoundedConfig{Policy: memory.EvictLFU, MaxEntries: 2}, nil)
+
+	addLinks(t, repo, "aaaaa", "bbbbb")
+	for range 3 {
+		assert.True(t, stored(repo, "aaaaa"))
+	}
+	assert.True(t, stored(repo, "bbbbb"))
+	addLinks(t, repo, "ccccc")
+
+	// bbbbb is the most recent but the least frequently used
+	assert.False(t, stored(repo, "bbbbb"))
+	assert.True(t, stored(repo, "aaaaa"))
+}
+
+func TestBoundedLinksRepo_MaxBytes(t *testing.T) {
+	repo := newBoundedLinksRepo(t, memory.BoundedConfig{Policy: memory.EvictLRU, MaxBytes: 1000}, nil)
+
+	for i := range 100 {
+		addLinks(t, repo, fmt.Sprintf("link%d", i))
+	}
+	assert.Less(t, repo.Len(), 10)
+	assert.Equal(t, uint64(100-repo.Len()), repo.Evictions())
+	assert.True(t, stored(repo, "link99"))
+}
+
+func TestBoundedLinksRepo_Semantics(t *testing.T) {
+	ctx := context.Background()
+	repo := newBoundedLinksRepo(t, memory.BoundedConfig{Policy: memory.EvictLRU, MaxEntries: 10}, nil)
+
+	addLinks(t, repo, "aaaaa")
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com/aaaaa"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/other"})
+	assert.ErrorIs(t, err, repository.ErrShortURLExists)
+
+	assert.NoError(t, repo.SetDisabled(ctx, "aaaaa", true))
+	link, err := repo.GetByShortLink(ctx, "aaaaa")
+	assert.NoError(t, err)
+	assert.True(t, link.Disabled)
+
+	assert.NoError(t, repo.Delete(ctx, "aaaaa"))
+	assert.ErrorIs(t, repo.Delete(ctx, "aaaaa"), repository.ErrShortURLNotFound)
+	assert.ErrorIs(t, repo.SetDisabled(ctx, "aaaaa", true), repository.ErrShortURLNotFound)
+}
+
+func TestBoundedLinksRepo_Edge(t *testing.T) {
+	ctx := context.Background()
+	next := memory.NewMemoryLinksRepo()
+	edge := newBoundedLinksRepo(t, memory.BoundedConfig{Policy: memory.EvictLRU, MaxEntries: 1, TTL: time.Hour}, next)
+
+	addLinks(t, edge, "aaaaa", "bbbbb")
+	assert.Equal(t, 1, edge.Len())
+
+	// Evicted links are read through from the next repository
+	assert.True(t, stored(edge, "aaaaa"))
+	assert.True(t, stored(edge, "bbbbb"))
+
+	// Changes are written through and drop the local copy
+	assert.NoError(t, edge.SetDisabled(ctx, "bbbbb", true))
+	link, err := edge.GetByShortLink(ctx, "bbbbb")
+	assert.NoError(t, err)
+	assert.True(t, link.Disabled)
+
+	assert.NoError(t, edge.Delete(ctx, "bbbbb"))
+	assert.False(t, stored(next, "bbbbb"))
+	assert.False(t, stored(edge, "bbbbb"))
+}
+
+func TestBoundedLinksRepo_Concurrent(t *testing.T) {
+	repo := newBoundedLinksRepo(t, memory.BoundedConfig{Policy: memory.EvictLFU, MaxEntries: 50}, nil)
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				shortLink := fmt.Sprintf("w%dl%d", w, i)
+				_, err := repo.Add(context.Background(), domain.Link{ShortLink: shortLink, OriginalURL: "https://example.com/" + shortLink})
+				assert.NoError(t, err)
+				stored(repo, shortLink)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, repo.Len())
+	assert.Equal(t, uint64(8*200-50), repo.Evictions())
+}
+
+func TestBoundedLinksRepo_InvalidConfig(t *testing.T) {
+	_, err := memory.NewBoundedLinksRepo(memory.BoundedConfig{Policy: memory.EvictLRU}, nil)
+	assert.ErrorIs(t, err, memory.ErrInvalidBoundedConfig)
+	_, err = memory.NewBoundedLinksRepo(memory.BoundedConfig{Policy: "fifo", MaxEntries: 1}, nil)
+	assert.ErrorIs(t, err, memory.ErrInvalidBoundedConfig)
+	_, err = memory.NewBoundedLinksRepo(memory.BoundedConfig{Policy: memory.EvictLRU, MaxEntries: 1}, memory.NewMemoryLinksRepo())
+	assert.ErrorIs(t, err, memory.ErrInvalidBoundedConfig)
+}
+
+// slowLinksRepo signals every link read on read and holds it until release is closed.
+type slowLinksRepo struct {
+	repository.LinksRepo
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (r *slowLinksRepo) GetByShortLink(ctx context.Context, shortLink string) (*domain.Link, error) {
+	link, err := r.LinksRepo.GetByShortLink(ctx, shortLink)
+	select {
+	case r.read <- struct{}{}:
+	default:
+	}
+	<-r.release
+	return link, err
+}
+
+// TestBoundedLinksRepo_EdgeStaleRead deletes a link while it is read through, the link
+// read before the delete must not be cached.
+func TestBoundedLinksRepo_EdgeStaleRead(t *testing.T) {
+	ctx := context.Background()
+	inner := memory.NewMemoryLinksRepo()
+	next := &slowLinksRepo{LinksRepo: inner, read: make(chan struct{}, 1), release: make(chan struct{})}
+	edge := newBoundedLinksRepo(t, memory.BoundedConfig{Policy: memory.EvictLRU, MaxEntries: 10, TTL: time.Hour}, next)
+	addLinks(t, inner, "aaaaa")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		link, err := edge.GetByShortLink(ctx, "aaaaa")
+		assert.NoError(t, err)
+		assert.Equal(t, "aaaaa", link.ShortLink)
+	}()
+	<-next.read
+	require.NoError(t, edge.Delete(ctx, "aaaaa"))
+	close(next.release)
+	<-done
+
+	assert.Equal(t, 0, edge.Len())
+	_, err := edge.GetByShortLink(ctx, "aaaaa")
+	assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+}
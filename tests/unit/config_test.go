@@ -0,0 +1,146 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/lib/generator"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_BatchMaxSize(t *testing.T) {
+	t.Setenv("APP_BATCH_MAX_SIZE", "21845")
+	_, err := config.LoadConfig()
+	assert.NoError(t, err)
+
+	// A larger batch would exceed the bind parameters of a single Postgres statement
+	t.Setenv("APP_BATCH_MAX_SIZE", "21846")
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, "APP_BATCH_MAX_SIZE")
+}
+
+func TestLoadConfig_Analytics(t *testing.T) {
+	t.Setenv("ANALYTICS_BATCH_SIZE", "13107")
+	_, err := config.LoadConfig()
+	assert.NoError(t, err)
+
+	// A larger batch would exceed the bind parameters of a single Postgres statement
+	t.Setenv("ANALYTICS_BATCH_SIZE", "13108")
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, "ANALYTICS_BATCH_SIZE")
+
+	t.Setenv("ANALYTICS_BATCH_SIZE", "500")
+	t.Setenv("ANALYTICS_FLUSH_INTERVAL_MS", "0")
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, "ANALYTICS_FLUSH_INTERVAL_MS")
+}
+
+func TestLoadConfig_CounterName(t *testing.T) {
+	t.Setenv("APP_LINK_GENERATOR", "sequence")
+	t.Setenv("APP_LINK_SEQUENCE_KEY", "secret")
+	t.Setenv("APP_LINK_COUNTER", "postgres")
+	t.Setenv("APP_LINK_COUNTER_NAME", "link_ids")
+	_, err := config.LoadConfig()
+	assert.NoError(t, err)
+
+	// The name is put into CREATE SEQUENCE as is
+	t.Setenv("APP_LINK_COUNTER_NAME", "ids; DROP TABLE links")
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, "APP_LINK_COUNTER_NAME")
+}
+
+func TestLoadConfig_Alphabet(t *testing.T) {
+	t.Setenv("APP_LINK_ALPHABET", "ab")
+	_, err := config.LoadConfig()
+	assert.NoError(t, err)
+
+	t.Setenv("APP_LINK_ALPHABET", "a")
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, "APP_LINK_ALPHABET")
+
+	// Only look-alikes are left once the ambiguous characters are removed
+	t.Setenv("APP_LINK_ALPHABET", "0oO")
+	t.Setenv("APP_LINK_UNAMBIGUOUS", "true")
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, "APP_LINK_ALPHABET")
+}
+
+func TestLoadConfig_RedisPrefix(t *testing.T) {
+	t.Setenv("REDIS_STORAGE_PREFIX", "links:")
+	_, err := config.LoadConfig()
+	assert.NoError(t, err)
+
+	// "abcurls" would be a valid short link and a cache key of its own
+	for _, prefix := range []string{"abc", "abc-"} {
+		t.Setenv("REDIS_STORAGE_PREFIX", prefix)
+		_, err = config.LoadConfig()
+		assert.ErrorContains(t, err, "REDIS_STORAGE_PREFIX", prefix)
+	}
+}
+
+func TestLoadConfig_EdgeTTL(t *testing.T) {
+	t.Setenv("MEMORY_EDGE", "true")
+	t.Setenv("MEMORY_MAX_ENTRIES", "1000")
+	t.Setenv("MEMORY_EDGE_TTL_MS", "60000")
+	_, err := config.LoadConfig()
+	assert.NoError(t, err)
+
+	// Without a TTL the changes of other instances would never be seen
+	t.Setenv("MEMORY_EDGE_TTL_MS", "0")
+	_, err = config.LoadConfig()
+	assert.ErrorContains(t, err, "MEMORY_EDGE_TTL_MS")
+}
+
+func TestValidateStorage_MemoryCounter(t *testing.T) {
+	t.Setenv("APP_LINK_GENERATOR", "sequence")
+	t.Setenv("APP_LINK_SEQUENCE_KEY", "secret")
+	t.Setenv("APP_LINK_COUNTER", "memory")
+	cfg, err := config.LoadConfig()
+	assert.NoError(t, err)
+
+	// Links stored in memory are lost together with the counter
+	assert.NoError(t, cfg.ValidateStorage("memory"))
+	for _, storageType := range []string{"postgres", "sqlite", "redis"} {
+		assert.ErrorContains(t, cfg.ValidateStorage(storageType), "APP_LINK_COUNTER", storageType)
+	}
+	cfg.Memory.DataDir = t.TempDir()
+	assert.ErrorContains(t, cfg.ValidateStorage("memory"), "APP_LINK_COUNTER")
+
+	cfg.App.LinkCounter = "postgres"
+	assert.NoError(t, cfg.ValidateStorage("postgres"))
+}
+
+// TestSequenceGenerator_MemoryCounterRestart shows why the memory counter is rejected with
+// persistent storage: after a restart it reissues the short links that are stored.
+func TestSequenceGenerator_MemoryCounterRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	codes := func() []string {
+		g, err := generator.NewSequenceGenerator(testAlphabet, generator.NewAtomicCounter(0), []byte("secret"))
+		require.NoError(t, err)
+		return []string{generate(t, g, generator.Request{Size: 10}), generate(t, g, generator.Request{Size: 10})}
+	}
+
+	repo, err := memory.NewDurableLinksRepo(dir, testDurableConfig)
+	require.NoError(t, err)
+	for i, code := range codes() {
+		_, err := repo.Add(ctx, domain.Link{ShortLink: code, OriginalURL: fmt.Sprintf("https://example.com/%d", i)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.Close())
+
+	repo, err = memory.NewDurableLinksRepo(dir, testDurableConfig)
+	require.NoError(t, err)
+	defer repo.Close()
+	for _, code := range codes() {
+		_, err := repo.Add(ctx, domain.Link{ShortLink: code, OriginalURL: "https://example.com/new"})
+		assert.ErrorIs(t, err, repository.ErrShortURLExists)
+	}
+}
@@ -0,0 +1,96 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/repository/postgres"
+	"url-shortener/internal/repository/repotest"
+	"url-shortener/internal/repository/sqlite"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConformance_Memory(t *testing.T) {
+	repotest.TestLinksRepo(t, func(t *testing.T) repository.LinksRepo {
+		return memory.NewMemoryLinksRepo()
+	})
+}
+
+func TestConformance_Durable(t *testing.T) {
+	repotest.TestLinksRepo(t, func(t *testing.T) repository.LinksRepo {
+		repo, err := memory.NewDurableLinksRepo(t.TempDir(), testDurableConfig)
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestConformance_Bounded(t *testing.T) {
+	repotest.TestLinksRepo(t, func(t *testing.T) repository.LinksRepo {
+		repo, err := memory.NewBoundedLinksRepo(memory.BoundedConfig{Policy: memory.EvictLRU, MaxEntries: 1000}, nil)
+		require.NoError(t, err)
+		return repo
+	})
+}
+
+func TestConformance_Edge(t *testing.T) {
+	repotest.TestLinksRepo(t, func(t *testing.T) repository.LinksRepo {
+		repo, err := memory.NewBoundedLinksRepo(memory.BoundedConfig{Policy: memory.EvictLFU, MaxEntries: 1000, TTL: time.Minute}, memory.NewMemoryLinksRepo())
+		require.NoError(t, err)
+		return repo
+	})
+}
+
+// TestConformance_SQLite allows writers a longer wait, the stress tests queue them all on
+// the single database lock and -race slows each of them down.
+func TestConformance_SQLite(t *testing.T) {
+	repotest.TestLinksRepo(t, func(t *testing.T) repository.LinksRepo {
+		repo, err := sqlite.NewSQLiteLinksRepo(filepath.Join(t.TempDir(), "links.db"), "links", 5*time.Second)
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestConformance_Redis(t *testing.T) {
+	repotest.TestLinksRepo(t, func(t *testing.T) repository.LinksRepo {
+		return newRedisLinksRepo(t)
+	})
+}
+
+// TestConformance_Postgres runs against the database at POSTGRES_TEST_DSN and is skipped
+// when it is not set. Every test migrates its own schema, dropped afterwards.
+func TestConformance_Postgres(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+
+	repotest.TestLinksRepo(t, func(t *testing.T) repository.LinksRepo {
+		schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+		conn := postgres.ConnConfig{DSN: dsn, Schema: schema, MaxOpenConns: 10, MaxIdleConns: 10}
+		tables := postgres.Tables{Schema: schema, Links: "links", Clicks: "link_clicks", Keys: "link_keys", MaxLength: 32}
+
+		repo, err := postgres.NewPostgresLinksRepo(conn, tables, true, 5*time.Second)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			repo.Close()
+			db, err := postgres.OpenDB(postgres.ConnConfig{DSN: dsn}, 5*time.Second)
+			if err != nil {
+				t.Logf("failed to drop schema %s: %v", schema, err)
+				return
+			}
+			defer db.Close()
+			if _, err := db.ExecContext(context.Background(), fmt.Sprintf(`DROP SCHEMA %s CASCADE`, schema)); err != nil {
+				t.Logf("failed to drop schema %s: %v", schema, err)
+			}
+		})
+		return repo
+	})
+}
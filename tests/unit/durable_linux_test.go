@@ -0,0 +1,60 @@
+package services_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limitFileSize caps the size of the files the process writes until the returned function
+// is called, a write crossing the limit stores what fits and fails with EFBIG. The Go
+// runtime ignores the SIGXFSZ it raises.
+func limitFileSize(t *testing.T, size uint64) func() {
+	var prev syscall.Rlimit
+	require.NoError(t, syscall.Getrlimit(syscall.RLIMIT_FSIZE, &prev))
+	require.NoError(t, syscall.Setrlimit(syscall.RLIMIT_FSIZE, &syscall.Rlimit{Cur: size, Max: prev.Max}))
+	return func() { require.NoError(t, syscall.Setrlimit(syscall.RLIMIT_FSIZE, &prev)) }
+}
+
+// TestDurableLinksRepo_FailedWrite fails an append half way, the partial record must not
+// hide the changes acknowledged after it when the log is replayed.
+func TestDurableLinksRepo_FailedWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := openDurableLinksRepo(t, dir)
+	defer repo.Close()
+
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/a"})
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, "links.log"))
+	require.NoError(t, err)
+
+	restore := limitFileSize(t, uint64(info.Size())+10)
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com/b"})
+	restore()
+	assert.Error(t, err)
+	assert.False(t, stored(repo, "bbbbb"))
+
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "ccccc", OriginalURL: "https://example.com/c"})
+	require.NoError(t, err)
+
+	// Replay a copy of the log, as a crash would leave it
+	data, err := os.ReadFile(filepath.Join(dir, "links.log"))
+	require.NoError(t, err)
+	crashed := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(crashed, "links.log"), data, 0o644))
+	replayed := openDurableLinksRepo(t, crashed)
+	defer replayed.Close()
+
+	assert.True(t, stored(replayed, "aaaaa"))
+	assert.True(t, stored(replayed, "ccccc"))
+	_, err = replayed.GetByShortLink(ctx, "bbbbb")
+	assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+}
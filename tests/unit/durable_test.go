@@ -0,0 +1,136 @@
+package services_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDurableConfig = memory.DurableConfig{
+	Fsync:            memory.FsyncAlways,
+	SnapshotInterval: time.Hour,
+}
+
+func openDurableLinksRepo(t *testing.T, dir string) *memory.DurableLinksRepo {
+	repo, err := memory.NewDurableLinksRepo(dir, testDurableConfig)
+	require.NoError(t, err)
+	return repo
+}
+
+func TestDurableLinksRepo_Replay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := openDurableLinksRepo(t, dir)
+
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/a", ExpiresAt: &expiresAt})
+	assert.NoError(t, err)
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com/b"})
+	assert.NoError(t, err)
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "ccccc", OriginalURL: "https://example.com/c"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.SetDisabled(ctx, "bbbbb", true))
+	assert.NoError(t, repo.Delete(ctx, "ccccc"))
+
+	// Close snapshots the links, the next changes are only in the log
+	assert.NoError(t, repo.Close())
+	repo = openDurableLinksRepo(t, dir)
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "ddddd", OriginalURL: "https://example.com/d"})
+	assert.NoError(t, err)
+
+	// Simulate a crash by reopening without closing
+	repo = openDurableLinksRepo(t, dir)
+	defer repo.Close()
+
+	link, err := repo.GetByShortLink(ctx, "aaaaa")
+	assert.NoError(t, err)
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*link.ExpiresAt))
+
+	link, err = repo.GetByShortLink(ctx, "bbbbb")
+	assert.NoError(t, err)
+	assert.True(t, link.Disabled)
+
+	_, err = repo.GetByShortLink(ctx, "ccccc")
+	assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+
+	_, err = repo.GetByShortLink(ctx, "ddddd")
+	assert.NoError(t, err)
+
+	// Deduplication survives the restart
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "eeeee", OriginalURL: "https://example.com/b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "bbbbb", shortLink)
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/e"})
+	assert.ErrorIs(t, err, repository.ErrShortURLExists)
+}
+
+func TestDurableLinksRepo_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := openDurableLinksRepo(t, dir)
+
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/a"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Snapshot())
+
+	info, err := os.Stat(filepath.Join(dir, "links.log"))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	repo = openDurableLinksRepo(t, dir)
+	defer repo.Close()
+	_, err = repo.GetByShortLink(ctx, "aaaaa")
+	assert.NoError(t, err)
+}
+
+func TestDurableLinksRepo_TruncatesCorruptedTail(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := openDurableLinksRepo(t, dir)
+
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/a"})
+	assert.NoError(t, err)
+
+	path := filepath.Join(dir, "links.log")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// A record cut short by a crash
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0x20, 0x00, 0x00, 0x00, 0xde, 0xad})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	repo = openDurableLinksRepo(t, dir)
+	_, err = repo.GetByShortLink(ctx, "aaaaa")
+	assert.NoError(t, err)
+
+	truncated, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, info.Size(), truncated.Size())
+
+	// New records follow the last valid one
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com/b"})
+	assert.NoError(t, err)
+	repo = openDurableLinksRepo(t, dir)
+	defer repo.Close()
+	_, err = repo.GetByShortLink(ctx, "bbbbb")
+	assert.NoError(t, err)
+}
+
+func TestDurableLinksRepo_InvalidConfig(t *testing.T) {
+	_, err := memory.NewDurableLinksRepo(t.TempDir(), memory.DurableConfig{Fsync: "sometimes", SnapshotInterval: time.Hour})
+	assert.ErrorIs(t, err, memory.ErrInvalidDurableConfig)
+	_, err = memory.NewDurableLinksRepo(t.TempDir(), memory.DurableConfig{Fsync: memory.FsyncInterval, SnapshotInterval: time.Hour})
+	assert.ErrorIs(t, err, memory.ErrInvalidDurableConfig)
+}
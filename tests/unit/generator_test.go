@@ -0,0 +1,327 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/lib/generator"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"
+
+func TestHashGenerator_Deterministic(t *testing.T) {
+	g, err := generator.NewHashGenerator(testAlphabet, []byte("secret"))
+	assert.NoError(t, err)
+	other, err := generator.NewHashGenerator(testAlphabet, []byte("another secret"))
+	assert.NoError(t, err)
+
+	req := generator.Request{OriginalURL: "https://example.com/page?a=1", Size: 10}
+	code := generate(t, g, req)
+	assert.Len(t, code, 10)
+	for _, ch := range code {
+		assert.True(t, strings.ContainsRune(testAlphabet, ch))
+	}
+
+	assert.Equal(t, code, generate(t, g, req))
+	assert.Equal(t, code, generate(t, g, generator.Request{OriginalURL: "HTTPS://Example.COM:443/page?a=1", Size: 10}))
+	assert.NotEqual(t, code, generate(t, other, req))
+
+	// Collisions are resolved by salting, every attempt yields its own code.
+	salted := generate(t, g, generator.Request{OriginalURL: req.OriginalURL, Size: 10, Attempt: 1})
+	assert.NotEqual(t, code, salted)
+	assert.Equal(t, salted, generate(t, g, generator.Request{OriginalURL: req.OriginalURL, Size: 10, Attempt: 1}))
+
+	// Codes longer than a single digest are still fully keyed.
+	assert.Len(t, generate(t, g, generator.Request{OriginalURL: req.OriginalURL, Size: 64}), 64)
+
+	_, err = generator.NewHashGenerator(testAlphabet, nil)
+	assert.ErrorIs(t, err, generator.ErrEmptyHashKey)
+}
+
+func generate(t *testing.T, g generator.Generator, req generator.Request) string {
+	t.Helper()
+	code, err := g.Generate(context.Background(), req)
+	assert.NoError(t, err)
+	return code
+}
+
+func TestNormalizeURL(t *testing.T) {
+	assert.Equal(t, "http://example.com/", generator.NormalizeURL("HTTP://EXAMPLE.com:80"))
+	assert.Equal(t, "https://example.com:8443/a?b=c#d", generator.NormalizeURL("https://Example.com:8443/a?b=c#d"))
+	assert.Equal(t, "https://[::1]/", generator.NormalizeURL("https://[::1]:443"))
+	assert.Equal(t, "not a url", generator.NormalizeURL("not a url"))
+}
+
+func TestSave_HashGeneratorCachedLink(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	g, err := generator.NewHashGenerator("abcdefghijklmnopqrstuvwxyz", []byte("secret"))
+	assert.NoError(t, err)
+	linkService, _ := services.NewLinkService(repo, cache, g, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	originalURL := "https://example.com/page"
+	shortLink := generate(t, g, generator.Request{OriginalURL: originalURL, Size: 10})
+
+	// A cached link is recognized without touching the repository.
+	cache.On("Get", shortLink).Return(originalURL, nil).Once()
+	result, err := linkService.Save(context.Background(), originalURL, services.SaveOptions{}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/"+shortLink, result)
+	repo.AssertNotCalled(t, "Add", mock.Anything)
+
+	// Otherwise the deterministic code is stored as usual.
+	cache.On("Get", shortLink).Return("", errors.New("cache miss")).Once()
+	repo.On("Add", mock.Anything).Return(shortLink, nil).Once()
+	cache.On("Set", shortLink, originalURL, mock.Anything).Return(nil).Once()
+	result, err = linkService.Save(context.Background(), originalURL, services.SaveOptions{}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/"+shortLink, result)
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func TestSequenceGenerator_Unique(t *testing.T) {
+	// "ab" with size 6 has 64 short links; ID 0 is never handed out by the counter.
+	g, err := generator.NewSequenceGenerator("ab", generator.NewAtomicCounter(0), []byte("secret"))
+	assert.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 1; i < 64; i++ {
+		code := generate(t, g, generator.Request{Size: 6})
+		assert.Len(t, code, 6)
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+	}
+
+	_, err = g.Generate(context.Background(), generator.Request{Size: 6})
+	assert.ErrorIs(t, err, generator.ErrSequenceExhausted)
+
+	_, err = generator.NewSequenceGenerator("ab", generator.NewAtomicCounter(0), nil)
+	assert.ErrorIs(t, err, generator.ErrEmptySequenceKey)
+}
+
+func TestSequenceGenerator_Obfuscated(t *testing.T) {
+	g, err := generator.NewSequenceGenerator(testAlphabet, generator.NewAtomicCounter(0), []byte("secret"))
+	assert.NoError(t, err)
+
+	first := generate(t, g, generator.Request{Size: 10})
+	second := generate(t, g, generator.Request{Size: 10})
+	assert.NotEqual(t, first, second)
+	// Consecutive IDs do not share a long common prefix or suffix.
+	assert.NotEqual(t, first[:5], second[:5])
+	assert.NotEqual(t, first[5:], second[5:])
+}
+
+// newRandomGenerator creates a random generator over a valid alphabet.
+func newRandomGenerator(t *testing.T, alphabet string) *generator.RandomGenerator {
+	g, err := generator.NewRandomGenerator(alphabet)
+	require.NoError(t, err)
+	return g
+}
+
+func TestGenerators_ShortAlphabet(t *testing.T) {
+	// One character would divide by zero or loop forever, and allow a single short link per size
+	for _, alphabet := range []string{"", "a"} {
+		_, err := generator.NewRandomGenerator(alphabet)
+		assert.ErrorIs(t, err, generator.ErrShortAlphabet)
+		_, err = generator.NewHashGenerator(alphabet, []byte("secret"))
+		assert.ErrorIs(t, err, generator.ErrShortAlphabet)
+		_, err = generator.NewSequenceGenerator(alphabet, generator.NewAtomicCounter(0), []byte("secret"))
+		assert.ErrorIs(t, err, generator.ErrShortAlphabet)
+	}
+}
+
+func TestRandomGenerator_Uniform(t *testing.T) {
+	// 256 is not a multiple of 3, a plain modulo would favour "a".
+	g := newRandomGenerator(t, "abc")
+	counts := make(map[rune]int)
+	for _, ch := range generate(t, g, generator.Request{Size: 30000}) {
+		counts[ch]++
+	}
+	for _, ch := range "abc" {
+		assert.InDelta(t, 10000, counts[ch], 500, string(ch))
+	}
+}
+
+func TestRandomGenerator_Concurrent(t *testing.T) {
+	g := newRandomGenerator(t, testAlphabet)
+
+	const workers, perWorker = 8, 500
+	codes := make(chan string, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				code, err := g.Generate(context.Background(), generator.Request{Size: 10})
+				assert.NoError(t, err)
+				codes <- code
+			}
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	seen := make(map[string]bool)
+	for code := range codes {
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+	}
+	assert.Equal(t, uint64(workers*perWorker), g.Stats().Generated)
+}
+
+func TestSave_ReportsCollisions(t *testing.T) {
+	repo := new(MockRepo)
+	g := newRandomGenerator(t, "abcdefghijklmnopqrstuvwxyz")
+	linkService, _ := services.NewLinkService(repo, nil, g, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	repo.On("Add", mock.Anything).Return("", repository.ErrShortURLExists).Once()
+	// The retry finds the URL already shortened, which skips the cache.
+	repo.On("Add", mock.Anything).Return("existingab", nil).Once()
+
+	result, err := linkService.Save(context.Background(), "https://example.com", services.SaveOptions{}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/existingab", result)
+
+	stats := g.Stats()
+	assert.Equal(t, uint64(2), stats.Generated)
+	assert.Equal(t, uint64(1), stats.Collisions)
+	assert.Equal(t, 0.5, stats.CollisionRate())
+}
+
+// sequenceGenerator returns the given codes in order, one per call.
+type sequenceGenerator struct {
+	codes    []string
+	attempts []int
+}
+
+func (g *sequenceGenerator) Generate(_ context.Context, req generator.Request) (string, error) {
+	g.attempts = append(g.attempts, req.Attempt)
+	code := g.codes[0]
+	g.codes = g.codes[1:]
+	return code, nil
+}
+
+func TestFilteredGenerator_Blocklist(t *testing.T) {
+	next := &sequenceGenerator{codes: []string{"xxBADxx", "x8aDxx", "xb4dxx", "x0Oxxx", "xxgeek"}}
+	g := generator.NewFilteredGenerator(next, testAlphabet, []string{"bad", " ", "# not a word"}, true)
+
+	// "b4d" spells the word with a digit and "x0Oxxx" mixes look-alikes.
+	assert.Equal(t, "x8aDxx", generate(t, g, generator.Request{Size: 6, Attempt: 1}))
+	assert.Equal(t, "xxgeek", generate(t, g, generator.Request{Size: 6}))
+	assert.Equal(t, []int{16, 17, 0, 1, 2}, next.attempts)
+
+	rejecting := generator.NewFilteredGenerator(&sequenceGenerator{codes: strings.Split(strings.Repeat("bad,", 16), ",")}, testAlphabet, []string{"bad"}, false)
+	_, err := rejecting.Generate(context.Background(), generator.Request{Size: 3})
+	assert.ErrorIs(t, err, generator.ErrFilterExhausted)
+}
+
+func TestBlocklistFile(t *testing.T) {
+	path := t.TempDir() + "/blocklist.txt"
+	assert.NoError(t, os.WriteFile(path, []byte("# words\nbad\n\n  worse  \n"), 0o600))
+
+	words, err := generator.LoadBlocklist(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bad", "worse"}, words)
+
+	_, err = generator.LoadBlocklist(path + ".missing")
+	assert.Error(t, err)
+}
+
+func TestUnambiguous(t *testing.T) {
+	assert.Equal(t, "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ0123456789_", generator.UnambiguousAlphabet(testAlphabet))
+	assert.Equal(t, "abcO", generator.UnambiguousAlphabet("abcoO"))
+
+	g := generator.NewFilteredGenerator(newRandomGenerator(t, generator.UnambiguousAlphabet(testAlphabet)), testAlphabet, nil, true)
+	assert.Equal(t, "a01b10", g.Canonical("aOlbIo"))
+	for i := 0; i < 100; i++ {
+		code := generate(t, g, generator.Request{Size: 10})
+		assert.Equal(t, code, g.Canonical(code))
+	}
+}
+
+func TestGetOriginalURL_Canonical(t *testing.T) {
+	repo := new(MockRepo)
+	g := generator.NewFilteredGenerator(newRandomGenerator(t, "abc01"), "abc01lIoO", nil, true)
+	linkService, _ := services.NewLinkService(repo, nil, g, "abc01lIoO", testLengthPolicy, "example.com", testAliasPolicy)
+
+	repo.On("GetByShortLink", "abc0Ilabca").Return(nil, repository.ErrShortURLNotFound).Once()
+	repo.On("GetByShortLink", "abc011abca").Return(&domain.Link{ShortLink: "abc011abca", OriginalURL: "https://example.com"}, nil).Once()
+
+	originalURL, err := linkService.GetOriginalURL(context.Background(), "abc0Ilabca")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", originalURL)
+	repo.AssertExpectations(t)
+}
+
+func TestWordGenerator(t *testing.T) {
+	adjectives, nouns := generator.DefaultWordLists()
+	g, err := generator.NewWordGenerator(adjectives, nouns, 2)
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		code := generate(t, g, generator.Request{Size: 10})
+		parts := strings.Split(code, "-")
+		assert.Len(t, parts, 3, code)
+		assert.Contains(t, adjectives, parts[0])
+		assert.Contains(t, nouns, parts[1])
+		assert.Len(t, parts[2], 2)
+		assert.True(t, g.Valid(code), code)
+		assert.LessOrEqual(t, len(code), g.MaxLength())
+	}
+
+	assert.True(t, g.Valid("brave-otter-07"))
+	for _, code := range []string{"brave-otter", "brave-otter-7", "Brave-otter-42", "brave-otter-4x", "abcdefghij", "brave--42"} {
+		assert.False(t, g.Valid(code), code)
+	}
+
+	noDigits, err := generator.NewWordGenerator([]string{"brave"}, []string{"otter"}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "brave-otter", generate(t, noDigits, generator.Request{}))
+	assert.Equal(t, 11, noDigits.MaxLength())
+
+	_, err = generator.NewWordGenerator([]string{"brave"}, []string{"sea-otter"}, 2)
+	assert.ErrorIs(t, err, generator.ErrInvalidWordList)
+	_, err = generator.NewWordGenerator(nil, nouns, 2)
+	assert.ErrorIs(t, err, generator.ErrInvalidWordList)
+}
+
+func TestSave_WordsStyle(t *testing.T) {
+	repo := new(MockRepo)
+	words, err := generator.NewWordGenerator([]string{"brave"}, []string{"otter"}, 2)
+	assert.NoError(t, err)
+	linkService, _ := services.NewLinkService(repo, nil, &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	linkService.RegisterStyle("words", words)
+
+	var stored string
+	repo.On("Add", mock.MatchedBy(func(link domain.Link) bool {
+		stored = link.ShortLink
+		return words.Valid(link.ShortLink)
+	})).Return("brave-otter-42", nil).Once()
+
+	result, err := linkService.Save(context.Background(), "https://example.com", services.SaveOptions{Style: "words"}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/brave-otter-42", result)
+	assert.True(t, strings.HasPrefix(stored, "brave-otter-"))
+
+	_, err = linkService.Save(context.Background(), "https://example.com", services.SaveOptions{Style: "emoji"}, 3)
+	assert.ErrorIs(t, err, services.ErrInvalidStyle)
+
+	// Word links are accepted when resolving even though '-' is not in the alphabet.
+	repo.On("GetByShortLink", "brave-otter-42").Return(&domain.Link{ShortLink: "brave-otter-42", OriginalURL: "https://example.com"}, nil).Once()
+	originalURL, err := linkService.GetOriginalURL(context.Background(), "brave-otter-42")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", originalURL)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,69 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/health"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/routers"
+	"url-shortener/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingChecker simulates an unreachable backend.
+type failingChecker struct{}
+
+func (failingChecker) HealthCheck(context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	links := memory.NewMemoryLinksRepo()
+	linkService, err := services.NewLinkService(links, nil, &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	assert.NoError(t, err)
+	analyticsService, err := services.NewAnalyticsService(memory.NewMemoryClicksRepo(), links, 10, 10, time.Hour)
+	assert.NoError(t, err)
+	defer analyticsService.Close()
+
+	registry := health.NewRegistry(time.Second)
+	registry.Register("storage", links)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := routers.InitRouter(log, linkService, analyticsService, registry, metrics.New(), config.AppConfig{RedirectCode: http.StatusFound, BatchMaxSize: 10})
+
+	readiness := func() (int, map[string]any) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	code, body := readiness()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "OK", body["checks"].(map[string]any)["storage"].(map[string]any)["status"])
+
+	registry.Register("cache", failingChecker{})
+	code, body = readiness()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	checks := body["checks"].(map[string]any)
+	assert.Equal(t, "OK", checks["storage"].(map[string]any)["status"])
+	assert.Equal(t, "connection refused", checks["cache"].(map[string]any)["error"])
+}
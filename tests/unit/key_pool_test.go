@@ -0,0 +1,160 @@
+package services_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/lib/generator"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryKeysRepo(t *testing.T) {
+	ctx := context.Background()
+	links := memory.NewMemoryLinksRepo()
+	_, err := links.Add(ctx, domain.Link{ShortLink: "taken", OriginalURL: "https://example.com"})
+	assert.NoError(t, err)
+
+	keys := memory.NewMemoryKeysRepo(links)
+	added, err := keys.AddKeys(ctx, []string{"aaaaa", "taken", "bbbbb", "aaaaa"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	leased, err := keys.LeaseKeys(ctx, 5)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"aaaaa", "bbbbb"}, leased)
+
+	count, err := keys.CountKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestKeyPool(t *testing.T) {
+	ctx := context.Background()
+	keys := memory.NewMemoryKeysRepo(memory.NewMemoryLinksRepo())
+	pool, err := services.NewKeyPool(keys, newRandomGenerator(t, testAlphabet), services.KeyPoolConfig{
+		KeySize:       10,
+		LeaseSize:     5,
+		LowWater:      20,
+		RefillSize:    50,
+		CheckInterval: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	assert.Eventually(t, func() bool { return pool.Depth() >= 20 }, time.Second, 5*time.Millisecond)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		key, err := pool.Generate(ctx, generator.Request{Size: 10})
+		assert.NoError(t, err)
+		assert.Len(t, key, 10)
+		assert.False(t, seen[key], "duplicate key %s", key)
+		seen[key] = true
+	}
+
+	// The pool is topped up in the background after leases drain it.
+	assert.Eventually(t, func() bool {
+		count, err := keys.CountKeys(ctx)
+		return err == nil && count >= 20
+	}, time.Second, 5*time.Millisecond)
+	assert.Less(t, pool.Leased(), 5)
+
+	_, err = services.NewKeyPool(keys, newRandomGenerator(t, testAlphabet), services.KeyPoolConfig{KeySize: 10})
+	assert.ErrorIs(t, err, services.ErrInvalidKeyPoolConfig)
+}
+
+// slowKeysRepo holds every lease until release is closed.
+type slowKeysRepo struct {
+	repository.KeysRepo
+	release chan struct{}
+	leases  atomic.Int32
+}
+
+func (r *slowKeysRepo) LeaseKeys(ctx context.Context, n int) ([]string, error) {
+	r.leases.Add(1)
+	<-r.release
+	return r.KeysRepo.LeaseKeys(ctx, n)
+}
+
+func TestKeyPool_LeaseOutsideLock(t *testing.T) {
+	ctx := context.Background()
+	inner := memory.NewMemoryKeysRepo(memory.NewMemoryLinksRepo())
+	_, err := inner.AddKeys(ctx, []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc", "dddddddddd"})
+	require.NoError(t, err)
+	keys := &slowKeysRepo{KeysRepo: inner, release: make(chan struct{})}
+	pool, err := services.NewKeyPool(keys, newRandomGenerator(t, testAlphabet), services.KeyPoolConfig{
+		KeySize:       10,
+		LeaseSize:     4,
+		LowWater:      0,
+		RefillSize:    1,
+		CheckInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	results := make(chan string, 4)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := pool.Generate(ctx, generator.Request{Size: 10})
+			assert.NoError(t, err)
+			results <- key
+		}()
+	}
+	assert.Eventually(t, func() bool { return keys.leases.Load() == 1 }, time.Second, time.Millisecond)
+
+	// Requests the pool does not serve are not held up by the running lease.
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	key, err := pool.Generate(waitCtx, generator.Request{Size: 12})
+	require.NoError(t, err)
+	assert.Len(t, key, 12)
+
+	close(keys.release)
+	wg.Wait()
+	close(results)
+
+	var got []string
+	for key := range results {
+		got = append(got, key)
+	}
+	assert.ElementsMatch(t, []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc", "dddddddddd"}, got)
+	assert.Equal(t, int32(1), keys.leases.Load(), "waiting requests share one lease")
+}
+
+func TestKeyPool_ForwardsGenerator(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryLinksRepo()
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "abc011abca", OriginalURL: "https://example.com"})
+	require.NoError(t, err)
+
+	filtered := generator.NewFilteredGenerator(newRandomGenerator(t, "abc01"), "abc01lIoO", nil, true)
+	pool, err := services.NewKeyPool(memory.NewMemoryKeysRepo(repo), filtered, services.KeyPoolConfig{
+		KeySize:       10,
+		LeaseSize:     5,
+		LowWater:      0,
+		RefillSize:    1,
+		CheckInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Equal(t, "abc011abca", pool.Canonical("abc0Ilabca"))
+	assert.Same(t, filtered, pool.Unwrap())
+
+	linkService, err := services.NewLinkService(repo, nil, pool, "abc01lIoO", testLengthPolicy, "example.com", testAliasPolicy)
+	require.NoError(t, err)
+	originalURL, err := linkService.GetOriginalURL(ctx, "abc0Ilabca")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", originalURL)
+}
@@ -1,10 +1,14 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 	"url-shortener/internal/domain"
+	"url-shortener/internal/lib/generator"
 	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/memory"
 	"url-shortener/internal/services"
 
 	"github.com/stretchr/testify/assert"
@@ -16,12 +20,12 @@ type MockRepo struct {
 	mock.Mock
 }
 
-func (m *MockRepo) Add(link domain.Link) (string, error) {
+func (m *MockRepo) Add(_ context.Context, link domain.Link) (string, error) {
 	args := m.Called(link)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockRepo) GetByShortLink(shortLink string) (*domain.Link, error) {
+func (m *MockRepo) GetByShortLink(_ context.Context, shortLink string) (*domain.Link, error) {
 	args := m.Called(shortLink)
 	if args.Get(0) != nil {
 		return args.Get(0).(*domain.Link), args.Error(1)
@@ -29,43 +33,70 @@ func (m *MockRepo) GetByShortLink(shortLink string) (*domain.Link, error) {
 	return nil, args.Error(1)
 }
 
+func (m *MockRepo) AddBatch(_ context.Context, links []domain.Link) ([]repository.AddResult, error) {
+	args := m.Called(links)
+	if args.Get(0) != nil {
+		return args.Get(0).([]repository.AddResult), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRepo) Delete(_ context.Context, shortLink string) error {
+	args := m.Called(shortLink)
+	return args.Error(0)
+}
+
+func (m *MockRepo) SetDisabled(_ context.Context, shortLink string, disabled bool) error {
+	args := m.Called(shortLink, disabled)
+	return args.Error(0)
+}
+
 // MockCache simulates cache behavior
 type MockCache struct {
 	mock.Mock
 }
 
-func (c *MockCache) Get(key string) (string, error) {
+func (c *MockCache) Get(_ context.Context, key string) (string, error) {
 	args := c.Called(key)
 	return args.String(0), args.Error(1)
 }
 
-func (c *MockCache) Set(key, value string) error {
-	args := c.Called(key, value)
+func (c *MockCache) Set(_ context.Context, key, value string, maxTTL time.Duration) error {
+	args := c.Called(key, value, maxTTL)
+	return args.Error(0)
+}
+
+func (c *MockCache) Delete(_ context.Context, key string) error {
+	args := c.Called(key)
 	return args.Error(0)
 }
 
+var testLengthPolicy = services.LengthPolicy{Initial: 10, Min: 10, Max: 10}
+
+var testAliasPolicy = services.AliasPolicy{MinLength: 4, MaxLength: 16, Reserved: []string{"api", "swagger"}}
+
 // MockGenerator simulates the link generator behavior
 type MockGenerator struct {
 	alphabet string
 }
 
-func (g *MockGenerator) Generate(size int) string {
-	return g.alphabet[:size]
+func (g *MockGenerator) Generate(_ context.Context, req generator.Request) (string, error) {
+	return g.alphabet[:req.Size], nil
 }
 
 func TestSave_Success(t *testing.T) {
 	repo := new(MockRepo)
 	cache := new(MockCache)
 	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
-	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", 10, "example.com")
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
 
 	originalURL := "https://example.com"
 	shortLink := "abcdefghij"
 
 	repo.On("Add", mock.Anything).Return(shortLink, nil).Once()
-	cache.On("Set", shortLink, originalURL).Return(nil)
+	cache.On("Set", shortLink, originalURL, time.Duration(0)).Return(nil)
 
-	result, err := linkService.Save(originalURL, 3)
+	result, err := linkService.Save(context.Background(), originalURL, services.SaveOptions{}, 3)
 	assert.NoError(t, err)
 	assert.Equal(t, "https://example.com/abcdefghij", result)
 	repo.AssertExpectations(t)
@@ -76,10 +107,10 @@ func TestSave_InvalidURL(t *testing.T) {
 	repo := new(MockRepo)
 	cache := new(MockCache)
 	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
-	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", 10, "example.com")
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
 
 	invalidURL := "invalid-url"
-	result, err := linkService.Save(invalidURL, 3)
+	result, err := linkService.Save(context.Background(), invalidURL, services.SaveOptions{}, 3)
 	assert.ErrorIs(t, err, services.ErrInvalidURL)
 	assert.Empty(t, result)
 }
@@ -88,14 +119,14 @@ func TestGetOriginalURL_CacheHit(t *testing.T) {
 	repo := new(MockRepo)
 	cache := new(MockCache)
 	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
-	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", 10, "example.com")
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
 
 	shortLink := "abcdefghij"
 	originalURL := "https://example.com"
 
 	cache.On("Get", shortLink).Return("https://example.com", nil).Once()
 
-	result, err := linkService.GetOriginalURL(shortLink)
+	result, err := linkService.GetOriginalURL(context.Background(), shortLink)
 	assert.NoError(t, err)
 	assert.Equal(t, originalURL, result)
 	cache.AssertExpectations(t)
@@ -105,7 +136,7 @@ func TestGetOriginalURL_CacheMiss_RepoHit(t *testing.T) {
 	repo := new(MockRepo)
 	cache := new(MockCache)
 	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
-	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", 10, "example.com")
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
 
 	shortLink := "abcdefghij"
 	originalURL := "https://example.com"
@@ -113,7 +144,7 @@ func TestGetOriginalURL_CacheMiss_RepoHit(t *testing.T) {
 	cache.On("Get", shortLink).Return("", errors.New("cache miss"))
 	repo.On("GetByShortLink", shortLink).Return(&domain.Link{ShortLink: shortLink, OriginalURL: originalURL}, nil).Once()
 
-	result, err := linkService.GetOriginalURL(shortLink)
+	result, err := linkService.GetOriginalURL(context.Background(), shortLink)
 	assert.NoError(t, err)
 	assert.Equal(t, originalURL, result)
 	repo.AssertExpectations(t)
@@ -124,15 +155,244 @@ func TestGetOriginalURL_NotFound(t *testing.T) {
 	repo := new(MockRepo)
 	cache := new(MockCache)
 	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
-	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", 10, "example.com")
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
 
 	shortLink := "nonexisten"
 	cache.On("Get", shortLink).Return("", errors.New("cache miss"))
 	repo.On("GetByShortLink", shortLink).Return(nil, repository.ErrShortURLNotFound).Once()
 
-	result, err := linkService.GetOriginalURL(shortLink)
+	result, err := linkService.GetOriginalURL(context.Background(), shortLink)
 	assert.ErrorIs(t, err, services.ErrNotFound)
 	assert.Empty(t, result)
 	repo.AssertExpectations(t)
 	cache.AssertExpectations(t)
 }
+
+func TestSave_Alias(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	originalURL := "https://example.com"
+	repo.On("Add", domain.Link{ShortLink: "promo", OriginalURL: originalURL}).Return("promo", nil).Once()
+	cache.On("Set", "promo", originalURL, time.Duration(0)).Return(nil)
+
+	result, err := linkService.Save(context.Background(), originalURL, services.SaveOptions{Alias: "promo"}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/promo", result)
+	repo.AssertExpectations(t)
+}
+
+func TestSave_AliasRejected(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	originalURL := "https://example.com"
+
+	_, err := linkService.Save(context.Background(), originalURL, services.SaveOptions{Alias: "ab"}, 3)
+	assert.ErrorIs(t, err, services.ErrInvalidAlias)
+
+	_, err = linkService.Save(context.Background(), originalURL, services.SaveOptions{Alias: "promo-2024"}, 3)
+	assert.ErrorIs(t, err, services.ErrInvalidAlias)
+
+	_, err = linkService.Save(context.Background(), originalURL, services.SaveOptions{Alias: "swagger"}, 3)
+	assert.ErrorIs(t, err, services.ErrReservedAlias)
+
+	repo.On("Add", domain.Link{ShortLink: "taken", OriginalURL: originalURL}).Return("", repository.ErrShortURLExists).Once()
+	_, err = linkService.Save(context.Background(), originalURL, services.SaveOptions{Alias: "taken"}, 3)
+	assert.ErrorIs(t, err, services.ErrAliasTaken)
+
+	repo.On("Add", domain.Link{ShortLink: "fresh", OriginalURL: originalURL}).Return("abcdefghij", nil).Once()
+	_, err = linkService.Save(context.Background(), originalURL, services.SaveOptions{Alias: "fresh"}, 3)
+	assert.ErrorIs(t, err, services.ErrURLExists)
+
+	repo.AssertExpectations(t)
+}
+
+func TestSave_WithExpiration(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	originalURL := "https://example.com"
+	expiresAt := time.Now().Add(time.Hour)
+
+	repo.On("Add", domain.Link{ShortLink: "abcdefghij", OriginalURL: originalURL, ExpiresAt: &expiresAt}).Return("abcdefghij", nil).Once()
+	cache.On("Set", "abcdefghij", originalURL, mock.MatchedBy(func(ttl time.Duration) bool {
+		return ttl > 0 && ttl <= time.Hour
+	})).Return(nil).Once()
+
+	result, err := linkService.Save(context.Background(), originalURL, services.SaveOptions{ExpiresAt: &expiresAt}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/abcdefghij", result)
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = linkService.Save(context.Background(), originalURL, services.SaveOptions{ExpiresAt: &past}, 3)
+	assert.ErrorIs(t, err, services.ErrInvalidExpiry)
+}
+
+func TestSave_ExistingURLWithAnotherExpiration(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryLinksRepo()
+	cache := new(MockCache)
+	cache.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	generator := &sequenceGenerator{codes: []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc", "dddddddddd"}}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	originalURL := "https://example.com"
+	expiresAt := time.Now().Add(time.Hour)
+	result, err := linkService.Save(ctx, originalURL, services.SaveOptions{ExpiresAt: &expiresAt}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/aaaaaaaaaa", result)
+
+	// The same expiration, or none, gets the existing link
+	result, err = linkService.Save(ctx, originalURL, services.SaveOptions{ExpiresAt: &expiresAt}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/aaaaaaaaaa", result)
+	result, err = linkService.Save(ctx, originalURL, services.SaveOptions{}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/aaaaaaaaaa", result)
+
+	// Another expiration is not silently ignored
+	later := expiresAt.Add(time.Hour)
+	_, err = linkService.Save(ctx, originalURL, services.SaveOptions{ExpiresAt: &later}, 3)
+	assert.ErrorIs(t, err, services.ErrExpiryConflict)
+
+	link, err := repo.GetByShortLink(ctx, "aaaaaaaaaa")
+	assert.NoError(t, err)
+	assert.True(t, expiresAt.Equal(*link.ExpiresAt))
+}
+
+func TestGetOriginalURL_Expired(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	shortLink := "abcdefghij"
+	expiredAt := time.Now().Add(-time.Second)
+	cache.On("Get", shortLink).Return("", errors.New("cache miss"))
+	repo.On("GetByShortLink", shortLink).Return(&domain.Link{ShortLink: shortLink, OriginalURL: "https://example.com", ExpiresAt: &expiredAt}, nil).Once()
+
+	result, err := linkService.GetOriginalURL(context.Background(), shortLink)
+	assert.ErrorIs(t, err, services.ErrExpired)
+	assert.Empty(t, result)
+	repo.AssertExpectations(t)
+}
+
+func TestDelete_EvictsCache(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	repo.On("Delete", "abcdefghij").Return(nil).Once()
+	cache.On("Delete", "abcdefghij").Return(nil).Once()
+	assert.NoError(t, linkService.Delete(context.Background(), "abcdefghij"))
+
+	repo.On("Delete", "nonexisten").Return(repository.ErrShortURLNotFound).Once()
+	assert.ErrorIs(t, linkService.Delete(context.Background(), "nonexisten"), services.ErrNotFound)
+
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func TestGetOriginalURL_Disabled(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	shortLink := "abcdefghij"
+	repo.On("SetDisabled", shortLink, true).Return(nil).Once()
+	cache.On("Delete", shortLink).Return(nil).Once()
+	assert.NoError(t, linkService.SetDisabled(context.Background(), shortLink, true))
+
+	cache.On("Get", shortLink).Return("", errors.New("cache miss"))
+	repo.On("GetByShortLink", shortLink).Return(&domain.Link{ShortLink: shortLink, OriginalURL: "https://example.com", Disabled: true}, nil).Once()
+
+	result, err := linkService.GetOriginalURL(context.Background(), shortLink)
+	assert.ErrorIs(t, err, services.ErrDisabled)
+	assert.Empty(t, result)
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func TestSaveBatch_PartialFailures(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	urls := []string{"https://a.example.com", "invalid-url", "https://b.example.com"}
+
+	repo.On("AddBatch", []domain.Link{
+		{ShortLink: "abcdefghij", OriginalURL: urls[0]},
+		{ShortLink: "abcdefghij", OriginalURL: urls[2]},
+	}).Return([]repository.AddResult{
+		{ShortLink: "abcdefghij"},
+		{Err: repository.ErrShortURLExists},
+	}, nil).Once()
+	repo.On("AddBatch", []domain.Link{
+		{ShortLink: "abcdefghij", OriginalURL: urls[2]},
+	}).Return([]repository.AddResult{
+		{Err: repository.ErrShortURLExists},
+	}, nil).Once()
+
+	results, err := linkService.SaveBatch(context.Background(), urls, 2)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, "https://example.com/abcdefghij", results[0].ShortURL)
+	assert.ErrorIs(t, results[1].Err, services.ErrInvalidURL)
+	assert.ErrorIs(t, results[2].Err, services.ErrMaxRetriesExceeded)
+	repo.AssertExpectations(t)
+}
+
+func TestSave_ContextCanceled(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, _ := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repo.On("Add", mock.Anything).Return("", context.Canceled).Once()
+
+	result, err := linkService.Save(ctx, "https://example.com", services.SaveOptions{}, 3)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, result)
+	repo.AssertExpectations(t)
+}
+
+func TestSave_GrowsLengthOnCollisions(t *testing.T) {
+	repo := new(MockRepo)
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	policy := services.LengthPolicy{Initial: 4, Min: 4, Max: 5, GrowthThreshold: 0.5, GrowthWindow: 2}
+	linkService, err := services.NewLinkService(repo, nil, generator, "abcdefghijklmnopqrstuvwxyz", policy, "example.com", testAliasPolicy)
+	assert.NoError(t, err)
+
+	repo.On("Add", mock.MatchedBy(func(link domain.Link) bool { return link.ShortLink == "abcd" })).Return("", repository.ErrShortURLExists).Twice()
+	repo.On("Add", mock.MatchedBy(func(link domain.Link) bool { return link.ShortLink == "abcde" })).Return("abcde", nil).Once()
+
+	result, err := linkService.Save(context.Background(), "https://example.com", services.SaveOptions{}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/abcde", result)
+	assert.Equal(t, 5, linkService.LinkLength())
+	repo.AssertExpectations(t)
+
+	// The length never grows past the maximum.
+	repo.On("Add", mock.Anything).Return("", repository.ErrShortURLExists)
+	_, err = linkService.Save(context.Background(), "https://example.org", services.SaveOptions{}, 4)
+	assert.ErrorIs(t, err, services.ErrMaxRetriesExceeded)
+	assert.Equal(t, 5, linkService.LinkLength())
+
+	_, err = services.NewLinkService(repo, nil, generator, "abc", services.LengthPolicy{Initial: 3, Min: 4, Max: 5}, "example.com", testAliasPolicy)
+	assert.ErrorIs(t, err, services.ErrInvalidLinkSize)
+}
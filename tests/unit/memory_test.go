@@ -0,0 +1,106 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLinksRepo_CollisionRetries(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryLinksRepo()
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaaaaaaa", OriginalURL: "https://example.com/taken"})
+	require.NoError(t, err)
+
+	cache := new(MockCache)
+	cache.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	gen := &sequenceGenerator{codes: []string{"aaaaaaaaaa", "bbbbbbbbbb"}}
+	linkService, err := services.NewLinkService(repo, cache, gen, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	require.NoError(t, err)
+
+	// The taken short link is a collision, the service retries with the next one
+	result, err := linkService.Save(ctx, "https://example.com/new", services.SaveOptions{}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/bbbbbbbbbb", result)
+
+	link, err := repo.GetByShortLink(ctx, "aaaaaaaaaa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/taken", link.OriginalURL)
+}
+
+func TestMemoryLinksRepo_AliasTaken(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryLinksRepo()
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "promo", OriginalURL: "https://example.com/taken"})
+	require.NoError(t, err)
+
+	linkService, err := services.NewLinkService(repo, new(MockCache), &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"},
+		"abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	require.NoError(t, err)
+
+	_, err = linkService.Save(ctx, "https://example.com/new", services.SaveOptions{Alias: "promo"}, 3)
+	assert.ErrorIs(t, err, services.ErrAliasTaken)
+}
+
+// TestMemoryLinksRepo_ConcurrentChurn adds and deletes one URL under many short links
+// while reading them, every link read must be the one of its URL.
+func TestMemoryLinksRepo_ConcurrentChurn(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryLinksRepo()
+	const (
+		workers = 8
+		rounds  = 200
+	)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				shortLink := fmt.Sprintf("churn%d", (w+i)%4)
+				switch i % 3 {
+				case 0:
+					if _, err := repo.Add(ctx, domain.Link{ShortLink: shortLink, OriginalURL: "https://example.com/churn"}); err != nil {
+						assert.ErrorIs(t, err, repository.ErrShortURLExists)
+					}
+				case 1:
+					if link, err := repo.GetByShortLink(ctx, shortLink); err == nil {
+						assert.Equal(t, shortLink, link.ShortLink)
+						assert.Equal(t, "https://example.com/churn", link.OriginalURL)
+					}
+				case 2:
+					if err := repo.Delete(ctx, shortLink); err != nil {
+						assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// At most one short link is left for the URL and the URL maps to it
+	var stored []string
+	for i := range 4 {
+		if _, err := repo.GetByShortLink(ctx, fmt.Sprintf("churn%d", i)); err == nil {
+			stored = append(stored, fmt.Sprintf("churn%d", i))
+		}
+	}
+	require.LessOrEqual(t, len(stored), 1)
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "fresh", OriginalURL: "https://example.com/churn"})
+	require.NoError(t, err)
+	if len(stored) == 1 {
+		assert.Equal(t, stored[0], shortLink)
+	} else {
+		assert.Equal(t, "fresh", shortLink)
+	}
+}
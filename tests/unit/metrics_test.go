@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/cache"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/health"
+	"url-shortener/internal/lib/generator"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/routers"
+	"url-shortener/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := metrics.New()
+	links := metrics.InstrumentLinksRepo(memory.NewMemoryLinksRepo(), "memory", m)
+	linkCache := new(MockCache)
+	linkCache.On("Get", "zzzzzzzzzz").Return("", cache.ErrMiss)
+	linkService, err := services.NewLinkService(links, metrics.InstrumentCache(linkCache, m), &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	assert.NoError(t, err)
+	analyticsService, err := services.NewAnalyticsService(memory.NewMemoryClicksRepo(), links, 10, 10, time.Hour)
+	assert.NoError(t, err)
+	defer analyticsService.Close()
+	m.RegisterDroppedClicks(analyticsService)
+
+	shortener := metrics.InstrumentShortener(linkService, m)
+	_, err = shortener.Save(context.Background(), "not a url", services.SaveOptions{}, 5)
+	assert.ErrorIs(t, err, services.ErrInvalidURL)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := routers.InitRouter(log, shortener, analyticsService, health.NewRegistry(time.Second), m, config.AppConfig{RedirectCode: http.StatusFound, BatchMaxSize: 10})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/link/zzzzzzzzzz", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`url_shortener_shorten_total{outcome="invalid_url"} 1`,
+		`url_shortener_http_requests_total{method="GET",route="/api/v1/link/:link",status="404"} 1`,
+		`url_shortener_repository_call_duration_seconds_count{backend="memory",operation="get"} 1`,
+		`url_shortener_cache_lookups_total{result="miss"} 1`,
+		`url_shortener_analytics_dropped_clicks_total 0`,
+		`go_goroutines`,
+	} {
+		assert.True(t, strings.Contains(body, want), "missing %s", want)
+	}
+}
+
+// TestMetrics_CollisionsAndProbes counts only the generated short links that were retried as
+// collisions, and leaves the cache probes of deterministic generators out of the lookups.
+func TestMetrics_CollisionsAndProbes(t *testing.T) {
+	ctx := context.Background()
+	m := metrics.New()
+	repo := memory.NewMemoryLinksRepo()
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaaaaaaa", OriginalURL: "https://example.com/taken"})
+	assert.NoError(t, err)
+	links := metrics.InstrumentLinksRepo(repo, "memory", m)
+	linkCache := new(MockCache)
+	linkCache.On("Get", mock.Anything).Return("", cache.ErrMiss)
+	linkCache.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	instrumentedCache := metrics.InstrumentCache(linkCache, m)
+
+	// A retried collision is counted, the conflicting alias is not
+	gen := &sequenceGenerator{codes: []string{"aaaaaaaaaa", "bbbbbbbbbb"}}
+	linkService, err := services.NewLinkService(links, instrumentedCache, gen, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	assert.NoError(t, err)
+	m.RegisterCollisions(linkService, "memory")
+	_, err = linkService.Save(ctx, "https://example.com/a", services.SaveOptions{}, 3)
+	assert.NoError(t, err)
+	_, err = linkService.Save(ctx, "https://example.com/b", services.SaveOptions{Alias: "bbbbbbbbbb"}, 3)
+	assert.ErrorIs(t, err, services.ErrAliasTaken)
+
+	// The deterministic generator probes the cache before saving
+	hashGenerator, err := generator.NewHashGenerator("abcdefghijklmnopqrstuvwxyz", []byte("key"))
+	assert.NoError(t, err)
+	hashService, err := services.NewLinkService(links, instrumentedCache, hashGenerator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	assert.NoError(t, err)
+	_, err = hashService.Save(ctx, "https://example.com/c", services.SaveOptions{}, 3)
+	assert.NoError(t, err)
+	linkCache.AssertCalled(t, "Get", mock.Anything)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `url_shortener_short_link_collisions_total{backend="memory"} 1`)
+	assert.NotContains(t, body, `url_shortener_cache_lookups_total`)
+}
@@ -0,0 +1,210 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/repository/postgres"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator_LoadsEmbeddedMigrations(t *testing.T) {
+	migrator, err := postgres.NewMigrator(nil, postgres.Tables{Links: "links", Clicks: "link_clicks", Keys: "link_keys", MaxLength: 32})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, migrator.Latest())
+
+	_, err = postgres.NewMigrator(nil, postgres.Tables{Links: "links", Clicks: "link_clicks", Keys: "link_keys"})
+	assert.Error(t, err, "the short link column needs a size")
+}
+
+func TestConnConfig_ConnString(t *testing.T) {
+	dsn, err := postgres.ConnConfig{
+		Host:             "db",
+		Port:             5432,
+		User:             "app",
+		Password:         "it's secret",
+		Name:             "links",
+		Schema:           "shortener",
+		StatementTimeout: 2 * time.Second,
+	}.ConnString()
+	assert.NoError(t, err)
+	assert.Equal(t, `host=db port=5432 user=app password='it\'s secret' dbname=links sslmode=disable search_path=shortener statement_timeout=2000`, dsn)
+
+	// The TLS settings apply on top of a DSN, which keeps its own mode otherwise
+	dsn, err = postgres.ConnConfig{
+		DSN:         "postgres://app:pw@db.example.com:6432/links?sslmode=require",
+		SSLMode:     "verify-full",
+		SSLRootCert: "/etc/ssl/ca.pem",
+	}.ConnString()
+	assert.NoError(t, err)
+	assert.Contains(t, dsn, "host='db.example.com'")
+	assert.Contains(t, dsn, "sslmode='require'")
+	assert.True(t, strings.HasSuffix(dsn, "sslmode=verify-full sslrootcert=/etc/ssl/ca.pem"))
+
+	_, err = postgres.ConnConfig{DSN: "postgres://%zz"}.ConnString()
+	assert.Error(t, err)
+}
+
+// newTestMigrator connects to the database at POSTGRES_TEST_DSN, skipping the test when it
+// is not set, and returns a migrator of a fresh schema that is dropped afterwards.
+func newTestMigrator(t *testing.T, maxLength int) (*postgres.Migrator, postgres.Tables) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+
+	schema := fmt.Sprintf("migrate_%d", time.Now().UnixNano())
+	db, err := postgres.OpenDB(postgres.ConnConfig{DSN: dsn, Schema: schema, MaxOpenConns: 10}, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(context.Background(), fmt.Sprintf(`DROP SCHEMA IF EXISTS %s CASCADE`, schema)); err != nil {
+			t.Logf("failed to drop schema %s: %v", schema, err)
+		}
+		db.Close()
+	})
+
+	tables := postgres.Tables{Schema: schema, Links: "links", Clicks: "link_clicks", Keys: "link_keys", MaxLength: maxLength}
+	migrator, err := postgres.NewMigrator(db, tables)
+	require.NoError(t, err)
+	return migrator, tables
+}
+
+func TestMigrator_UpDownUp(t *testing.T) {
+	ctx := context.Background()
+	migrator, _ := newTestMigrator(t, 32)
+
+	version, pending, err := migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.Equal(t, migrator.Latest(), pending)
+	assert.ErrorIs(t, migrator.Check(ctx), postgres.ErrSchemaOutdated)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), applied)
+	assert.NoError(t, migrator.Check(ctx))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied, "applied migrations are not repeated")
+
+	reverted, err := migrator.Down(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reverted)
+	version, pending, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest()-2, version)
+	assert.Equal(t, 2, pending)
+	assert.ErrorIs(t, migrator.Check(ctx), postgres.ErrSchemaOutdated)
+
+	// Reverting more than is applied stops at the first migration
+	reverted, err = migrator.Down(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest()-2, reverted)
+	version, _, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), applied)
+	assert.NoError(t, migrator.Check(ctx))
+}
+
+func TestMigrator_ConcurrentUp(t *testing.T) {
+	ctx := context.Background()
+	migrator, _ := newTestMigrator(t, 32)
+
+	// The advisory lock lets a single instance apply every migration
+	const instances = 4
+	applied := make([]int, instances)
+	errs := make([]error, instances)
+	var wg sync.WaitGroup
+	for i := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied[i], errs[i] = migrator.Up(ctx)
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range instances {
+		require.NoError(t, errs[i])
+		total += applied[i]
+	}
+	assert.Equal(t, migrator.Latest(), total)
+}
+
+// TestMigrator_WaitsPastStatementTimeout holds the migration lock longer than the statement
+// timeout of the connections, a migrating instance must wait for it rather than fail.
+func TestMigrator_WaitsPastStatementTimeout(t *testing.T) {
+	ctx := context.Background()
+	_, tables := newTestMigrator(t, 32)
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	db, err := postgres.OpenDB(postgres.ConnConfig{DSN: dsn, Schema: tables.Schema, MaxOpenConns: 1, StatementTimeout: 50 * time.Millisecond}, 5*time.Second)
+	require.NoError(t, err)
+	defer db.Close()
+	migrator, err := postgres.NewMigrator(db, tables)
+	require.NoError(t, err)
+
+	holder, err := postgres.OpenDB(postgres.ConnConfig{DSN: dsn}, 5*time.Second)
+	require.NoError(t, err)
+	defer holder.Close()
+	conn, err := holder.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	// The key of the migration lock
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock(7402519386)`)
+	require.NoError(t, err)
+	time.AfterFunc(300*time.Millisecond, func() {
+		conn.ExecContext(ctx, `SELECT pg_advisory_unlock(7402519386)`)
+	})
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	// The connection gets its statement timeout back
+	var timeout string
+	require.NoError(t, db.QueryRowContext(ctx, `SHOW statement_timeout`).Scan(&timeout))
+	assert.Equal(t, "50ms", timeout)
+}
+
+func TestMigrator_SizesShortLinks(t *testing.T) {
+	ctx := context.Background()
+	migrator, tables := newTestMigrator(t, 16)
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+
+	// A longer configured length outdates the schema until it is migrated
+	tables.MaxLength = 24
+	longer, err := postgres.NewMigrator(migratorDB(t, tables), tables)
+	require.NoError(t, err)
+	assert.ErrorIs(t, longer.Check(ctx), postgres.ErrSchemaOutdated)
+	applied, err := longer.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+	assert.NoError(t, longer.Check(ctx))
+
+	// A shorter one fits into the column
+	tables.MaxLength = 8
+	shorter, err := postgres.NewMigrator(migratorDB(t, tables), tables)
+	require.NoError(t, err)
+	assert.NoError(t, shorter.Check(ctx))
+}
+
+// migratorDB opens another connection pool to the schema of tables.
+func migratorDB(t *testing.T, tables postgres.Tables) *sql.DB {
+	db, err := postgres.OpenDB(postgres.ConnConfig{DSN: os.Getenv("POSTGRES_TEST_DSN"), Schema: tables.Schema, MaxOpenConns: 2}, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -0,0 +1,109 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/health"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/memory"
+	"url-shortener/internal/routers"
+	"url-shortener/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestRouter(t *testing.T, repo *MockRepo, cache *MockCache, clicks repository.ClicksRepo) (*gin.Engine, *services.AnalyticsService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	generator := &MockGenerator{alphabet: "abcdefghijklmnopqrstuvwxyz"}
+	linkService, err := services.NewLinkService(repo, cache, generator, "abcdefghijklmnopqrstuvwxyz", testLengthPolicy, "example.com", testAliasPolicy)
+	assert.NoError(t, err)
+
+	analyticsService, err := services.NewAnalyticsService(clicks, repo, 10, 10, time.Hour)
+	assert.NoError(t, err)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	appCfg := config.AppConfig{RedirectCode: http.StatusMovedPermanently, BatchMaxSize: 10}
+	return routers.InitRouter(log, linkService, analyticsService, health.NewRegistry(time.Second), metrics.New(), appCfg), analyticsService
+}
+
+func TestRedirect_Found(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	clicks := memory.NewMemoryClicksRepo()
+	r, analyticsService := newTestRouter(t, repo, cache, clicks)
+
+	shortLink := "abcdefghij"
+	cache.On("Get", shortLink).Return("", errors.New("cache miss"))
+	repo.On("GetByShortLink", shortLink).Return(&domain.Link{ShortLink: shortLink, OriginalURL: "https://example.com/page"}, nil)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/"+shortLink, nil))
+
+		assert.Equal(t, http.StatusMovedPermanently, w.Code, method)
+		assert.Equal(t, "https://example.com/page", w.Header().Get("Location"), method)
+	}
+
+	// Only the GET request counts as a click.
+	analyticsService.Close()
+	buckets, err := clicks.CountClicks(context.Background(), shortLink, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), time.Hour)
+	assert.NoError(t, err)
+	var total int64
+	for _, b := range buckets {
+		total += b.Count
+	}
+	assert.Equal(t, int64(1), total)
+}
+
+func TestRedirect_NotFound(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	r, _ := newTestRouter(t, repo, cache, memory.NewMemoryClicksRepo())
+
+	cache.On("Get", "nonexisten").Return("", errors.New("cache miss"))
+	repo.On("GetByShortLink", "nonexisten").Return(nil, repository.ErrShortURLNotFound)
+
+	for _, code := range []string{"nonexisten", "bad!"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code, code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html", code)
+	}
+}
+
+// TestSave_TTLSeconds resubmits a URL with the same ttl_seconds a second after it was
+// shortened, the deadline counted from the second request conflicts with the stored one.
+func TestSave_TTLSeconds(t *testing.T) {
+	repo := new(MockRepo)
+	cache := new(MockCache)
+	r, _ := newTestRouter(t, repo, cache, memory.NewMemoryClicksRepo())
+
+	stored := time.Now().Add(time.Hour - time.Second)
+	repo.On("Add", mock.Anything).Return("zzzzzzzzzz", nil).Once()
+	repo.On("GetByShortLink", "zzzzzzzzzz").Return(&domain.Link{ShortLink: "zzzzzzzzzz", OriginalURL: "https://example.com", ExpiresAt: &stored}, nil).Once()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/link/", strings.NewReader(`{"url": "https://example.com", "ttl_seconds": 3600}`)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	repo.AssertExpectations(t)
+
+	// A TTL beyond 100 years would overflow the deadline
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/link/", strings.NewReader(`{"url": "https://example.com", "ttl_seconds": 9223372036}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,149 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+	"url-shortener/internal/cache"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/redis"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedisLinksRepo connects to the Redis at REDIS_TEST_ADDR (host:port) and skips the test
+// when it is not set. Every test gets its own key prefix.
+func newRedisLinksRepo(t *testing.T) *redis.RedisLinksRepo {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	prefix := fmt.Sprintf("test:%s:%d:", t.Name(), time.Now().UnixNano())
+	repo, err := redis.NewRedisLinksRepo(host, port, "", 0, prefix, time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestRedisLinksRepo_Add(t *testing.T) {
+	ctx := context.Background()
+	repo := newRedisLinksRepo(t)
+
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+
+	shortLink, err = repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com/a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/b"})
+	assert.ErrorIs(t, err, repository.ErrShortURLExists)
+
+	results, err := repo.AddBatch(ctx, []domain.Link{
+		{ShortLink: "ccccc", OriginalURL: "https://example.com/c"},
+		{ShortLink: "ddddd", OriginalURL: "https://example.com/a"},
+		{ShortLink: "aaaaa", OriginalURL: "https://example.com/d"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.AddResult{
+		{ShortLink: "ccccc"},
+		{ShortLink: "aaaaa"},
+		{Err: repository.ErrShortURLExists},
+	}, results)
+}
+
+func TestRedisLinksRepo_ExpiryDeleteAndDisable(t *testing.T) {
+	ctx := context.Background()
+	repo := newRedisLinksRepo(t)
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com", ExpiresAt: &past})
+	assert.NoError(t, err)
+
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+
+	assert.NoError(t, repo.SetDisabled(ctx, "aaaaa", true))
+	link, err := repo.GetByShortLink(ctx, "aaaaa")
+	assert.NoError(t, err)
+	assert.Nil(t, link.ExpiresAt)
+	assert.True(t, link.Disabled)
+
+	assert.NoError(t, repo.Delete(ctx, "aaaaa"))
+	assert.ErrorIs(t, repo.Delete(ctx, "aaaaa"), repository.ErrShortURLNotFound)
+	assert.ErrorIs(t, repo.SetDisabled(ctx, "aaaaa", false), repository.ErrShortURLNotFound)
+	_, err = repo.GetByShortLink(ctx, "aaaaa")
+	assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+
+	shortLink, err = repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "bbbbb", shortLink)
+}
+
+// TestRedisLinksRepo_AddBatchAfterScriptFlush flushes the loaded scripts, as a Redis restart
+// would, batches must load them again instead of failing.
+func TestRedisLinksRepo_AddBatchAfterScriptFlush(t *testing.T) {
+	ctx := context.Background()
+	repo := newRedisLinksRepo(t)
+
+	client := goredis.NewClient(&goredis.Options{Addr: os.Getenv("REDIS_TEST_ADDR")})
+	defer client.Close()
+	require.NoError(t, client.ScriptFlush(ctx).Err())
+
+	results, err := repo.AddBatch(ctx, []domain.Link{
+		{ShortLink: "aaaaa", OriginalURL: "https://example.com/a"},
+		{ShortLink: "bbbbb", OriginalURL: "https://example.com/b"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []repository.AddResult{{ShortLink: "aaaaa"}, {ShortLink: "bbbbb"}}, results)
+
+	link, err := repo.GetByShortLink(ctx, "bbbbb")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", link.OriginalURL)
+}
+
+// TestRedisCounter_RejectsEvictingRedis switches the Redis at REDIS_TEST_ADDR to allkeys-lru
+// for a moment, a counter could be evicted there and start over.
+func TestRedisCounter_RejectsEvictingRedis(t *testing.T) {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := goredis.NewClient(&goredis.Options{Addr: addr})
+	defer client.Close()
+	prev, err := client.ConfigGet(ctx, "maxmemory-policy").Result()
+	require.NoError(t, err)
+	defer client.ConfigSet(ctx, "maxmemory-policy", prev[1].(string))
+
+	redisCache, err := cache.NewRedisCache(host, port, "", 0, 60, time.Second)
+	require.NoError(t, err)
+	defer redisCache.Close()
+
+	require.NoError(t, client.ConfigSet(ctx, "maxmemory-policy", "allkeys-lru").Err())
+	_, err = cache.NewRedisCounter(redisCache, "counter:test")
+	assert.ErrorIs(t, err, cache.ErrEvictingRedis)
+
+	require.NoError(t, client.ConfigSet(ctx, "maxmemory-policy", "volatile-lru").Err())
+	_, err = cache.NewRedisCounter(redisCache, "counter:test")
+	assert.NoError(t, err)
+}
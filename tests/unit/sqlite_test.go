@@ -0,0 +1,153 @@
+package services_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/repository/sqlite"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteLinksRepo(t *testing.T) *sqlite.SQLiteLinksRepo {
+	repo, err := sqlite.NewSQLiteLinksRepo(filepath.Join(t.TempDir(), "links.db"), "links", time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestSQLiteLinksRepo_Add(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteLinksRepo(t)
+
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+
+	// The same URL keeps its short link
+	shortLink, err = repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com/a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+
+	// A taken short link is a collision
+	_, err = repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/b"})
+	assert.ErrorIs(t, err, repository.ErrShortURLExists)
+
+	link, err := repo.GetByShortLink(ctx, "aaaaa")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", link.OriginalURL)
+	assert.Nil(t, link.ExpiresAt)
+
+	_, err = repo.GetByShortLink(ctx, "bbbbb")
+	assert.ErrorIs(t, err, repository.ErrShortURLNotFound)
+}
+
+func TestSQLiteLinksRepo_RevivesExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteLinksRepo(t)
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com", ExpiresAt: &past})
+	assert.NoError(t, err)
+
+	future := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com", ExpiresAt: &future})
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaa", shortLink)
+
+	link, err := repo.GetByShortLink(ctx, "aaaaa")
+	assert.NoError(t, err)
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, future.Equal(*link.ExpiresAt))
+}
+
+func TestSQLiteLinksRepo_AddBatch(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteLinksRepo(t)
+
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com/a"})
+	assert.NoError(t, err)
+
+	results, err := repo.AddBatch(ctx, []domain.Link{
+		{ShortLink: "bbbbb", OriginalURL: "https://example.com/b"},
+		{ShortLink: "ccccc", OriginalURL: "https://example.com/a"},
+		{ShortLink: "aaaaa", OriginalURL: "https://example.com/c"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.AddResult{
+		{ShortLink: "bbbbb"},
+		{ShortLink: "aaaaa"},
+		{Err: repository.ErrShortURLExists},
+	}, results)
+}
+
+func TestSQLiteLinksRepo_DeleteAndDisable(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteLinksRepo(t)
+
+	_, err := repo.Add(ctx, domain.Link{ShortLink: "aaaaa", OriginalURL: "https://example.com"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.SetDisabled(ctx, "aaaaa", true))
+	link, err := repo.GetByShortLink(ctx, "aaaaa")
+	assert.NoError(t, err)
+	assert.True(t, link.Disabled)
+
+	assert.NoError(t, repo.Delete(ctx, "aaaaa"))
+	assert.ErrorIs(t, repo.Delete(ctx, "aaaaa"), repository.ErrShortURLNotFound)
+	assert.ErrorIs(t, repo.SetDisabled(ctx, "aaaaa", false), repository.ErrShortURLNotFound)
+
+	// The URL can be shortened again after the deletion
+	shortLink, err := repo.Add(ctx, domain.Link{ShortLink: "bbbbb", OriginalURL: "https://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "bbbbb", shortLink)
+}
+
+func TestSQLiteLinksRepo_ConcurrentAdd(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteLinksRepo(t)
+
+	var wg sync.WaitGroup
+	shortLinks := make([]string, 8)
+	for i := range shortLinks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			shortLink, err := repo.Add(ctx, domain.Link{ShortLink: string(rune('a'+i)) + "xxxx", OriginalURL: "https://example.com"})
+			assert.NoError(t, err)
+			shortLinks[i] = shortLink
+		}(i)
+	}
+	wg.Wait()
+
+	for _, shortLink := range shortLinks {
+		assert.Equal(t, shortLinks[0], shortLink)
+	}
+}
+
+func TestSQLiteClicksRepo(t *testing.T) {
+	ctx := context.Background()
+	links := newSQLiteLinksRepo(t)
+	clicks, err := sqlite.NewSQLiteClicksRepo(links, "link_clicks")
+	require.NoError(t, err)
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, clicks.AddClicks(ctx, []domain.Click{
+		{ShortLink: "aaaaa", At: day.Add(time.Hour)},
+		{ShortLink: "aaaaa", At: day.Add(2 * time.Hour)},
+		{ShortLink: "aaaaa", At: day.Add(25 * time.Hour)},
+		{ShortLink: "bbbbb", At: day.Add(time.Hour)},
+	}))
+
+	buckets, err := clicks.CountClicks(ctx, "aaaaa", day, day.Add(48*time.Hour), 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ClickBucket{
+		{Start: day, Count: 2},
+		{Start: day.Add(24 * time.Hour), Count: 1},
+	}, buckets)
+}